@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ballotEmbed renders the ballot DMed to a voter in a secret poll
+func ballotEmbed(e *pollEntry, lang string) *discordgo.MessageEmbed {
+	lines := []string{}
+	for i, o := range e.Poll.Options {
+		lines = append(lines, fmt.Sprintf("**%d.** %s", i+1, o))
+	}
+
+	instructions := catalog.T(lang, "ballot.instructions", e.ID)
+	if e.Poll.MultiChoice {
+		instructions = catalog.T(lang, "ballot.instructions_multi", e.ID)
+	}
+	if e.Poll.Anonymous {
+		instructions += "\n" + catalog.T(lang, "ballot.anonymous")
+	}
+
+	title := e.Question
+	if title == "" {
+		title = catalog.T(lang, "poll.untitled")
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		Description: strings.Join(lines, "\n") + "\n\n" + instructions,
+		Color:       pollColor,
+		Footer:      &discordgo.MessageEmbedFooter{Text: catalog.T(lang, "ballot.footer", e.ID)},
+	}
+}
+
+// sendBallots DMs a ballot for poll id to each eligible voter, then reports
+// how many were delivered in the poll's channel
+func sendBallots(s *discordgo.Session, id int) {
+	var e pollEntry
+	if !polls.View(id, func(entry *pollEntry) { e = entry.snapshot() }) {
+		return
+	}
+
+	sent, failed := 0, 0
+	for _, user := range e.Eligible {
+		if err := sendDM(s, user, ballotEmbed(&e, language(e.GuildID, user))); err != nil {
+			fmt.Printf("failed to send ballot for poll %d: %v\n", id, err)
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	msg := catalog.Plural(language(e.GuildID, ""), "ballot.sent", sent, failed)
+	if _, err := s.ChannelMessageSend(e.ChannelID, msg); err != nil {
+		fmt.Printf("failed to report ballots for poll %d: %v\n", id, err)
+	}
+}
+
+// sendDM sends embed to userID in a direct message
+func sendDM(s *discordgo.Session, userID string, embed *discordgo.MessageEmbed) error {
+	c, err := s.UserChannelCreate(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.ChannelMessageSendEmbed(c.ID, embed)
+	return err
+}
+
+// openBallots returns the IDs of the open secret polls userID may vote in
+func openBallots(userID string) []int {
+	ids := []int{}
+	polls.Each(func(e *pollEntry) {
+		if e.Secret && !e.Closed && e.canVote(userID) {
+			ids = append(ids, e.ID)
+		}
+	})
+
+	return ids
+}
+
+// handleBallot records a reply to a ballot: option numbers, prefixed with the
+// poll ID when the voter has more than one open ballot
+func handleBallot(s *discordgo.Session, m *discordgo.MessageCreate) {
+	reply := func(key string, args ...interface{}) {
+		if _, err := s.ChannelMessageSend(m.ChannelID, catalog.T(language("", m.Author.ID), key, args...)); err != nil {
+			fmt.Printf("failed to reply to ballot: %v\n", err)
+		}
+	}
+
+	open := openBallots(m.Author.ID)
+	fields := strings.Fields(m.Content)
+	if len(open) == 0 || len(fields) == 0 {
+		reply("ballot.none")
+		return
+	}
+
+	id := open[0]
+	if strings.HasPrefix(fields[0], "#") || len(open) > 1 {
+		n, err := strconv.Atoi(strings.TrimPrefix(fields[0], "#"))
+		if err != nil || !containsInt(open, n) {
+			ids := []string{}
+			for _, o := range open {
+				ids = append(ids, "#"+strconv.Itoa(o))
+			}
+			reply("ballot.which_poll", strings.Join(ids, ", "), open[0])
+			return
+		}
+		id, fields = n, fields[1:]
+	}
+
+	var e pollEntry
+	polls.View(id, func(entry *pollEntry) { e = entry.snapshot() })
+	if err := checkVoter(s, &e, m.Author.ID); err != nil {
+		if ineligible, ok := err.(*IneligibleError); ok {
+			explainIneligible(s, &e, m.Author.ID, ineligible)
+			return
+		}
+		fmt.Printf("failed to check eligibility on poll %d: %v\n", id, err)
+		reply("command_failed")
+		return
+	}
+
+	var recorded []string
+	err := polls.Update(id, func(e *pollEntry) error {
+		// the poll may have closed since the voter's ballots were looked up
+		if e.Closed {
+			return newUserError("poll.already_closed", id)
+		}
+
+		choices := []string{}
+		for _, f := range fields {
+			n, err := strconv.Atoi(strings.Trim(f, ",."))
+			if err != nil || n < 1 || n > len(e.Poll.Options) {
+				return newUserError("ballot.invalid", len(e.Poll.Options))
+			}
+			if o := e.Poll.Options[n-1]; !contains(choices, o) {
+				choices = append(choices, o)
+			}
+		}
+		if len(choices) == 0 || (!e.Poll.MultiChoice && len(choices) > 1) {
+			return newUserError("ballot.invalid", len(e.Poll.Options))
+		}
+
+		if e.Poll.Anonymous {
+			if e.Poll.HasVoted(m.Author.ID) {
+				return newUserError("ballot.already_cast", e.ID)
+			}
+			recorded = choices
+			return e.Poll.Cast(m.Author.ID, choices...)
+		}
+
+		// a new ballot replaces the voter's previous one
+		for _, o := range e.Poll.Choices(m.Author.ID) {
+			e.Poll.Unvote(o, m.Author.ID)
+		}
+		for _, o := range choices {
+			if err := e.Poll.Vote(o, m.Author.ID); err != nil {
+				return err
+			}
+		}
+
+		recorded = choices
+		return nil
+	})
+	if err != nil {
+		if ue, ok := err.(*userError); ok {
+			reply(ue.key, ue.args...)
+			return
+		}
+		fmt.Printf("failed to record ballot for poll %d: %v\n", id, err)
+		reply("command_failed")
+		return
+	}
+
+	refresher.Refresh(s, id)
+	reply("ballot.recorded", id, strings.Join(recorded, ", "))
+	autoClosePoll(s, id)
+}
+
+// containsInt reports whether n is in list
+func containsInt(list []int, n int) bool {
+	for _, l := range list {
+		if l == n {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,219 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+)
+
+const (
+	width      = 800
+	padding    = 20
+	scale      = 2
+	titleScale = 3
+	rowHeight  = 40
+	barHeight  = 24
+	labelWidth = 260
+	valueWidth = 150
+	pieRadius  = 170
+	// maxLabelLines is how many lines a label wraps onto before it's cut short
+	maxLabelLines = 3
+)
+
+var (
+	background = color.RGBA{0x36, 0x39, 0x3f, 0xff}
+	foreground = color.RGBA{0xdc, 0xdd, 0xde, 0xff}
+	faded      = color.RGBA{0x72, 0x76, 0x7d, 0xff}
+
+	// palette colours bars and slices in order, wrapping around
+	palette = []color.RGBA{
+		{0x72, 0x89, 0xda, 0xff},
+		{0x43, 0xb5, 0x81, 0xff},
+		{0xfa, 0xa6, 0x1a, 0xff},
+		{0xf0, 0x47, 0x47, 0xff},
+		{0x9b, 0x59, 0xb6, 0xff},
+		{0x1a, 0xbc, 0x9c, 0xff},
+		{0xe9, 0x1e, 0x63, 0xff},
+		{0xf1, 0xc4, 0x0f, 0xff},
+		{0x34, 0x98, 0xdb, 0xff},
+		{0x95, 0xa5, 0xa6, 0xff},
+	}
+)
+
+// Bar is one option's value in a chart
+type Bar struct {
+	Label string
+	Value float64
+	// Text is shown next to the bar instead of Value, like "3 votes"
+	Text string
+}
+
+func (b Bar) text() string {
+	if b.Text != "" {
+		return Printable(b.Text)
+	}
+
+	return strconv.FormatFloat(b.Value, 'g', 4, 64)
+}
+
+// Encode writes img to w as a PNG
+func Encode(w io.Writer, img image.Image) error {
+	return png.Encode(w, img)
+}
+
+// fillRect fills r of img with c
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, &image.Uniform{c}, image.ZP, draw.Src)
+}
+
+// newCanvas creates a background filled image with title drawn at the top,
+// and returns the y coordinate below the title
+func newCanvas(height int, title string) (*image.RGBA, int) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fillRect(img, img.Bounds(), background)
+
+	title = fitText(Printable(title), width-2*padding, titleScale)
+	drawText(img, padding, padding, title, foreground, titleScale)
+
+	return img, titleHeight()
+}
+
+// titleHeight is the height newCanvas uses for the title
+func titleHeight() int {
+	return padding + cellHeight*titleScale + padding
+}
+
+// rowSize returns the height of a row whose text wraps onto lines lines
+func rowSize(lines int) int {
+	return rowHeight + (lines-1)*cellHeight*scale
+}
+
+// barLabel returns the label of b wrapped to fit beside its bar
+func barLabel(b Bar) []string {
+	return wrapText(Printable(b.Label), labelWidth-padding, scale, maxLabelLines)
+}
+
+// barsHeight returns the height drawBars takes for bars
+func barsHeight(bars []Bar) int {
+	height := 0
+	for _, b := range bars {
+		height += rowSize(len(barLabel(b)))
+	}
+
+	return height
+}
+
+// drawBars draws bars as horizontal rows starting at y, scaled so max fills
+// the available width
+func drawBars(img *image.RGBA, y int, bars []Bar, max float64) {
+	barArea := width - 2*padding - labelWidth - valueWidth
+	textOffset := (rowHeight - glyphHeight*scale) / 2
+
+	top := y
+	for i, b := range bars {
+		c := palette[i%len(palette)]
+		label := barLabel(b)
+		height := rowSize(len(label))
+
+		for j, line := range label {
+			drawText(img, padding, top+textOffset+j*cellHeight*scale, line, foreground, scale)
+		}
+
+		length := 0
+		if max > 0 {
+			length = int(math.Round(b.Value / max * float64(barArea)))
+		}
+		barTop := top + (height-barHeight)/2
+		fillRect(img, image.Rect(padding+labelWidth, barTop, padding+labelWidth+length, barTop+barHeight), c)
+
+		value := fitText(b.text(), valueWidth-padding, scale)
+		drawText(img, padding+labelWidth+length+padding/2, top+(height-glyphHeight*scale)/2, value, foreground, scale)
+
+		top += height
+	}
+}
+
+// maxValue returns the largest value of bars
+func maxValue(bars []Bar) float64 {
+	max := 0.0
+	for _, b := range bars {
+		max = math.Max(max, b.Value)
+	}
+
+	return max
+}
+
+// BarChart draws a horizontal bar for each of bars, in order
+func BarChart(title string, bars []Bar) *image.RGBA {
+	img, y := newCanvas(titleHeight()+barsHeight(bars)+padding, title)
+	drawBars(img, y, bars, maxValue(bars))
+
+	return img
+}
+
+// PieChart draws a pie with a slice for each of slices, with a legend beside it
+func PieChart(title string, slices []Bar) *image.RGBA {
+	legendX := padding + 2*pieRadius + 2*padding
+	square := glyphHeight * scale
+	legendWidth := width - legendX - square - 2*padding
+	legends := [][]string{}
+	legendHeight := 0
+	for _, s := range slices {
+		text := wrapText(Printable(s.Label)+" ("+s.text()+")", legendWidth, scale, maxLabelLines)
+		legends = append(legends, text)
+		legendHeight += rowSize(len(text))
+	}
+
+	height := titleHeight() + int(math.Max(float64(2*pieRadius), float64(legendHeight))) + padding
+	img, y := newCanvas(height, title)
+
+	total := 0.0
+	for _, s := range slices {
+		total += s.Value
+	}
+
+	cx, cy := padding+pieRadius, y+pieRadius
+	for py := cy - pieRadius; py < cy+pieRadius; py++ {
+		for px := cx - pieRadius; px < cx+pieRadius; px++ {
+			dx, dy := float64(px-cx)+0.5, float64(py-cy)+0.5
+			if dx*dx+dy*dy > pieRadius*pieRadius {
+				continue
+			}
+			if total == 0 {
+				img.Set(px, py, faded)
+				continue
+			}
+
+			// the fraction of the way round the circle clockwise from the top
+			angle := math.Atan2(dx, -dy) / (2 * math.Pi)
+			if angle < 0 {
+				angle++
+			}
+
+			sum := 0.0
+			for i, s := range slices {
+				sum += s.Value / total
+				if angle < sum || i == len(slices)-1 {
+					img.Set(px, py, palette[i%len(palette)])
+					break
+				}
+			}
+		}
+	}
+
+	textOffset := (rowHeight - square) / 2
+	top := y
+	for i, text := range legends {
+		fillRect(img, image.Rect(legendX, top+textOffset, legendX+square, top+textOffset+square), palette[i%len(palette)])
+		for j, line := range text {
+			drawText(img, legendX+square+padding/2, top+textOffset+j*cellHeight*scale, line, foreground, scale)
+		}
+		top += rowSize(len(text))
+	}
+
+	return img
+}
@@ -0,0 +1,175 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func TestPrintable(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{"pizza", "pizza"},
+		{"🍕 pizza", "\x7f pizza"},
+		{"Käse  Spätzle", "Kaese Spaetzle"},
+		{"¿Qué?", "?Que?"},
+		{"寿司", "??"},
+		{"🍕🌮", "\x7f\x7f"},
+		{"👍🏽 ❤️", "\x7f \x7f"},
+		{"👨‍👩‍👧 🇫🇷🇩🇪", "\x7f \x7f\x7f"},
+		{"1️⃣ one", "1 one"},
+		{"<:pizza:123> pizza <a:dance:456>", ":pizza: pizza :dance:"},
+	}
+
+	for _, test := range tests {
+		if got := Printable(test.s); got != test.expected {
+			t.Errorf("Printable(%q) returned incorrect text\nGot: %q\nWant: %q",
+				test.s, got, test.expected)
+		}
+	}
+}
+
+func TestFitText(t *testing.T) {
+	tests := []struct {
+		s        string
+		width    int
+		expected string
+	}{
+		{"pizza", 100, "pizza"},
+		{"a very long option name", 10 * cellWidth, "a very..."},
+		{"pizza", 2 * cellWidth, "pi"},
+	}
+
+	for _, test := range tests {
+		if got := fitText(test.s, test.width, 1); got != test.expected {
+			t.Errorf("fitText(%q, %d) returned incorrect text\nGot: %q\nWant: %q",
+				test.s, test.width, got, test.expected)
+		}
+	}
+}
+
+func TestOnlyEmoji(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected bool
+	}{
+		{"🍕", true},
+		{"🍕 🌮", true},
+		{"🍕 pizza", false},
+		{"<:pizza:123>", false},
+		{"", true},
+	}
+
+	for _, test := range tests {
+		if got := OnlyEmoji(test.s); got != test.expected {
+			t.Errorf("OnlyEmoji(%q) returned %v, want %v", test.s, got, test.expected)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		s        string
+		width    int
+		maxLines int
+		expected []string
+	}{
+		{"", 10 * cellWidth, 3, []string{""}},
+		{"pizza", 10 * cellWidth, 3, []string{"pizza"}},
+		{"a very long option name", 10 * cellWidth, 4, []string{"a very", "long", "option", "name"}},
+		{"pineapple pizza", 5 * cellWidth, 3, []string{"pinea", "pple", "pizza"}},
+		{"one two three four five", 5 * cellWidth, 3, []string{"one", "two", "th..."}},
+	}
+
+	for _, test := range tests {
+		got := wrapText(test.s, test.width, 1, test.maxLines)
+
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("wrapText(%q, %d) returned incorrect lines\nGot: %q\nWant: %q",
+				test.s, test.width, got, test.expected)
+		}
+	}
+}
+
+func TestBarChart(t *testing.T) {
+	bars := []Bar{
+		{Label: "pizza", Value: 3, Text: "3 votes"},
+		{Label: "tacos", Value: 0, Text: "0 votes"},
+	}
+	img := BarChart("What should we eat?", bars)
+
+	if h := img.Bounds().Dy(); h != titleHeight()+2*rowHeight+padding {
+		t.Errorf("BarChart returned an image %d pixels high", h)
+	}
+
+	// the longest bar spans the whole bar area
+	barY := titleHeight() + rowHeight/2
+	for _, x := range []int{padding + labelWidth, width - padding - valueWidth - 1} {
+		if c := img.RGBAAt(x, barY); c != palette[0] {
+			t.Errorf("BarChart pixel (%d, %d) is %v, want %v", x, barY, c, palette[0])
+		}
+	}
+
+	// an empty bar draws nothing
+	if c := img.RGBAAt(padding+labelWidth+1, barY+rowHeight); c != background {
+		t.Errorf("BarChart drew a bar for a zero value")
+	}
+}
+
+func TestPieChart(t *testing.T) {
+	slices := []Bar{
+		{Label: "yes", Value: 1},
+		{Label: "no", Value: 3},
+	}
+	img := PieChart("Poll", slices)
+
+	cx, cy := padding+pieRadius, titleHeight()+pieRadius
+	tests := []struct {
+		x, y  int
+		slice int
+	}{
+		// the first quarter clockwise from the top belongs to the first slice
+		{cx + pieRadius/2, cy - pieRadius/2, 0},
+		{cx - pieRadius/2, cy - pieRadius/2, 1},
+		{cx, cy + pieRadius/2, 1},
+	}
+
+	for _, test := range tests {
+		if c := img.RGBAAt(test.x, test.y); c != palette[test.slice] {
+			t.Errorf("PieChart pixel (%d, %d) is %v, want slice %d", test.x, test.y, c, test.slice)
+		}
+	}
+}
+
+func TestBarChartWrapsLabels(t *testing.T) {
+	bars := []Bar{
+		{Label: "a label far too long to fit on a single line beside its bar", Value: 1},
+		{Label: "short", Value: 2},
+	}
+	img := BarChart("Poll", bars)
+
+	expected := titleHeight() + rowSize(3) + rowHeight + padding
+	if h := img.Bounds().Dy(); h != expected {
+		t.Errorf("BarChart returned an image %d pixels high, want %d", h, expected)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	img := BarChart("Poll", []Bar{{Label: "a", Value: 2}, {Label: "b", Value: 1}})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatalf("Encode returned unexpected error: %v", err)
+	}
+
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Encode didn't write a valid PNG: %v", err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		t.Errorf("Encode wrote an image of %v, want %v", decoded.Bounds(), img.Bounds())
+	}
+}
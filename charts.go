@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/chart"
+)
+
+// chartKinds are the kinds of chart a poll's results can be drawn as
+var chartKinds = map[string]bool{"bar": true, "pie": true}
+
+// pollChart draws the votes on poll e as kind, a bar or pie chart
+func pollChart(e *pollEntry, kind, lang string) (*bytes.Buffer, error) {
+	bars := []chart.Bar{}
+	for i, o := range e.Poll.Options {
+		// emoji all look alike in a chart, so options of only emoji are
+		// numbered too
+		label := o
+		if chart.OnlyEmoji(label) {
+			label = catalog.T(lang, "poll.option", i+1) + " " + label
+		}
+
+		n := e.Poll.Count(o)
+		bars = append(bars, chart.Bar{
+			Label: label,
+			Value: float64(n),
+			Text:  catalog.Plural(lang, "votes", n),
+		})
+	}
+
+	title := e.Question
+	if chart.Printable(title) == "" {
+		title = catalog.T(lang, "poll.footer", e.ID)
+	}
+
+	img := chart.BarChart(title, bars)
+	if kind == "pie" {
+		img = chart.PieChart(title, bars)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := chart.Encode(buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// sendPollResults posts the results of poll e to channelID, with a chart
+// attached if the poll asked for one
+func sendPollResults(s *discordgo.Session, channelID string, e *pollEntry, lang string) error {
+	results := pollResults(e, lang)
+	if e.Chart == "" {
+		_, err := s.ChannelMessageSend(channelID, results)
+		return err
+	}
+
+	img, err := pollChart(e, e.Chart, lang)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.ChannelFileSendWithMessage(channelID, results, "results.png", img)
+	return err
+}
+
+func handlePollChart(ctx *Context) error {
+	id, err := pollArg(ctx)
+	if err != nil {
+		return err
+	}
+
+	kind := "bar"
+	if len(ctx.Args) > 1 {
+		kind = strings.ToLower(ctx.Args[1])
+		if !chartKinds[kind] {
+			return newUserError("poll.unknown_chart", kind)
+		}
+	}
+
+	var img *bytes.Buffer
+	found := polls.View(id, func(e *pollEntry) {
+		if e.inGuild(ctx.GuildID()) {
+			img, err = pollChart(e, kind, ctx.Lang())
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !found || img == nil {
+		return newUserError("poll.not_found", id)
+	}
+
+	_, err = ctx.Session.ChannelFileSend(ctx.Message.ChannelID, "results.png", img)
+	return err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mroseman95/discord-poll-bot/choose"
+)
+
+// pickCountPattern is the start of `choose 3 of a, b, c, d`
+var pickCountPattern = regexp.MustCompile(`(?i)^\s*(\d+)\s+of\s+`)
+
+func handleChoose(ctx *Context) error {
+	if m := pickCountPattern.FindStringSubmatch(ctx.Raw); m != nil {
+		return chooseMany(ctx, m[1], ctx.Raw[len(m[0]):])
+	}
+
+	options, err := parseChoices(ctx.Args)
+	if err != nil {
+		return err
+	}
+
+	option := options[choose.Pick(options, rand.Float64)].Name
+
+	fmt.Printf("%q chose: %s\n", ctx.Args, option)
+
+	reply := ctx.T("choose.result", option)
+	if choose.Weighted(options) {
+		reply += "\n" + ctx.T("choose.odds", formatOdds(options))
+	}
+	return ctx.Reply(reply)
+}
+
+// chooseMany picks count of the options in raw without repeats
+func chooseMany(ctx *Context, count, raw string) error {
+	args := splitArgs(raw, ",")
+	if len(args) == 0 {
+		return &UsageError{ctx.Command}
+	}
+
+	options, err := parseChoices(args)
+	if err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 || n > len(options) {
+		return newUserError("choose.invalid_count", count, len(options))
+	}
+
+	picked := choose.Sample(options, n, rand.Float64)
+	return replyList(ctx, ctx.Tn("choose.picked", n), options, picked, false)
+}
+
+func handleShuffle(ctx *Context) error {
+	options, err := parseChoices(ctx.Args)
+	if err != nil {
+		return err
+	}
+
+	order := choose.Sample(options, len(options), rand.Float64)
+	return replyList(ctx, ctx.T("shuffle.result"), options, order, false)
+}
+
+// handleOrder picks a turn order, for the players given or else for everyone
+// in the invoking user's voice channel
+func handleOrder(ctx *Context) error {
+	args, mention := ctx.Args, false
+	if len(args) == 0 {
+		if ctx.GuildID() == "" {
+			return &UsageError{ctx.Command}
+		}
+		_, occupants, err := voiceOccupants(ctx.Session, ctx.GuildID(), ctx.Message.Author.ID)
+		if err != nil {
+			return newUserError("order.not_in_voice")
+		}
+		args, mention = occupants, true
+	}
+
+	options, err := parseChoices(args)
+	if err != nil {
+		return err
+	}
+
+	order := choose.Sample(options, len(options), rand.Float64)
+	return replyList(ctx, ctx.T("order.result"), options, order, mention)
+}
+
+// parseChoices reads the options of the choose commands, weighted like
+// pizza:3 or tacos:25%
+func parseChoices(args []string) ([]choose.Option, error) {
+	options, err := choose.Parse(args)
+	if err, ok := err.(*choose.WeightError); ok {
+		return nil, newUserError("choose.invalid_weight", err.Option)
+	}
+
+	return options, err
+}
+
+// formatOdds lists each option with its chance of being picked
+func formatOdds(options []choose.Option) string {
+	odds := []string{}
+	for i, p := range choose.Odds(options) {
+		percent := strconv.FormatFloat(math.Round(p*1000)/10, 'f', -1, 64)
+		odds = append(odds, fmt.Sprintf("%s %s%%", options[i].Name, percent))
+	}
+
+	return strings.Join(odds, " · ")
+}
+
+// replyList replies with the options at indexes as a numbered list under
+// header, over as many messages as it takes. Options are user IDs to mention
+// when mention is set.
+func replyList(ctx *Context, header string, options []choose.Option, indexes []int, mention bool) error {
+	lines := []string{header}
+	for i, j := range indexes {
+		name := options[j].Name
+		if mention {
+			name = "<@" + name + ">"
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, name))
+	}
+
+	for _, msg := range joinLimited(lines, "\n", maxMessageLength) {
+		if err := ctx.Reply(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
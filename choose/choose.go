@@ -0,0 +1,219 @@
+// Package choose parses weighted options like pizza:3 or tacos:25% and picks
+// among them at random by their odds
+package choose
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Errors returned when parsing options
+var (
+	ErrNoOptions    = errors.New("no options to choose from")
+	ErrEmptyOption  = errors.New("an option has no name")
+	ErrMixedWeights = errors.New("percentages can't be mixed with plain weights")
+	ErrPercentTotal = errors.New("percentages must add up to 100")
+)
+
+// WeightError is returned for an option whose weight isn't a positive number
+type WeightError struct {
+	Option string
+}
+
+func (err *WeightError) Error() string {
+	return "invalid weight for option " + err.Option
+}
+
+var (
+	// weightPattern is what follows the colon of a weighted option, like 3,
+	// 0.5 or 25%
+	weightPattern = regexp.MustCompile(`^[-+]?(?:\d+\.?\d*|\.\d+)\s*%?$`)
+	// clockPattern is a time of day like 7:30, which isn't 7 weighted 30
+	clockPattern = regexp.MustCompile(`^\d{1,2}:\d{2}$`)
+)
+
+// percentEpsilon is how far off 100 percentages may add up to, for rounding
+const percentEpsilon = 0.01
+
+// Option is one of the things to choose from and how likely it is to be picked
+type Option struct {
+	Name string
+	// Weight is relative to the other options' weights
+	Weight float64
+	// Percent is set when the weight was given as a percentage
+	Percent bool
+	// Weighted is set when the option was given a weight at all
+	Weighted bool
+}
+
+// Parse reads options of the form name, name:weight or name:percent%. Options
+// without a weight count as 1, or when the others are percentages share what's
+// left of 100% equally. Text after the last colon that isn't a number, like in
+// a link or a custom emoji, or the minutes of a time like 7:30, is part of the
+// name.
+func Parse(args []string) ([]Option, error) {
+	options := []Option{}
+	for _, arg := range args {
+		o, err := parseOption(arg)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, o)
+	}
+	if len(options) == 0 {
+		return nil, ErrNoOptions
+	}
+
+	if err := spreadPercent(options); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// parseOption reads a single option
+func parseOption(arg string) (Option, error) {
+	arg = strings.TrimSpace(arg)
+	o := Option{Name: arg, Weight: 1}
+
+	i := strings.LastIndex(arg, ":")
+	if i >= 0 && weightPattern.MatchString(strings.TrimSpace(arg[i+1:])) && !clockPattern.MatchString(arg) {
+		o.Name = strings.TrimSpace(arg[:i])
+		weight := strings.TrimSpace(arg[i+1:])
+		if strings.HasSuffix(weight, "%") {
+			o.Percent = true
+			weight = strings.TrimSpace(strings.TrimSuffix(weight, "%"))
+		}
+
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil || w <= 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+			return Option{}, &WeightError{arg}
+		}
+		o.Weight, o.Weighted = w, true
+	}
+
+	if o.Name == "" {
+		return Option{}, ErrEmptyOption
+	}
+
+	return o, nil
+}
+
+// spreadPercent checks the percentages among options and shares what's left
+// of 100% between the options that have no weight
+func spreadPercent(options []Option) error {
+	total, percent, plain, unweighted := 0.0, false, false, 0
+	for _, o := range options {
+		switch {
+		case o.Percent:
+			percent = true
+			total += o.Weight
+		case o.Weighted:
+			plain = true
+		default:
+			unweighted++
+		}
+	}
+	if !percent {
+		return nil
+	}
+	if plain {
+		return ErrMixedWeights
+	}
+
+	left := 100 - total
+	if unweighted == 0 {
+		if math.Abs(left) > percentEpsilon {
+			return ErrPercentTotal
+		}
+		return nil
+	}
+	if left <= percentEpsilon {
+		return ErrPercentTotal
+	}
+
+	for i := range options {
+		if !options[i].Weighted {
+			options[i].Weight = left / float64(unweighted)
+		}
+	}
+
+	return nil
+}
+
+// Weighted reports whether any option was given a weight, so the odds aren't
+// all the same
+func Weighted(options []Option) bool {
+	for _, o := range options {
+		if o.Weighted {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Odds returns the chance of each option being picked, adding up to 1
+func Odds(options []Option) []float64 {
+	total := 0.0
+	for _, o := range options {
+		total += o.Weight
+	}
+
+	odds := make([]float64, len(options))
+	for i, o := range options {
+		odds[i] = o.Weight / total
+	}
+
+	return odds
+}
+
+// Pick returns the index of an option picked by the odds, random returning
+// numbers in [0, 1) like rand.Float64
+func Pick(options []Option, random func() float64) int {
+	total := 0.0
+	for _, o := range options {
+		total += o.Weight
+	}
+
+	x := random() * total
+	for i, o := range options {
+		if x < o.Weight {
+			return i
+		}
+		x -= o.Weight
+	}
+
+	// rounding can leave x just past the last weight
+	return len(options) - 1
+}
+
+// Sample returns the indexes of n options picked one after another without
+// picking any twice, each by its odds among the options left. Sampling every
+// option shuffles them, likelier options tending to come first.
+func Sample(options []Option, n int, random func() float64) []int {
+	left := make([]int, len(options))
+	for i := range left {
+		left[i] = i
+	}
+	if n > len(options) {
+		n = len(options)
+	}
+
+	picked := []int{}
+	for len(picked) < n {
+		remaining := make([]Option, len(left))
+		for i, j := range left {
+			remaining[i] = options[j]
+		}
+
+		i := Pick(remaining, random)
+		picked = append(picked, left[i])
+		left = append(left[:i], left[i+1:]...)
+	}
+
+	return picked
+}
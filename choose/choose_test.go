@@ -0,0 +1,179 @@
+package choose
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		args     []string
+		err      error
+		expected []Option
+	}{
+		{
+			[]string{"pizza", "tacos"},
+			nil,
+			[]Option{{"pizza", 1, false, false}, {"tacos", 1, false, false}},
+		},
+		{
+			[]string{"pizza:3", "tacos: 1", "sushi:0.5"},
+			nil,
+			[]Option{{"pizza", 3, false, true}, {"tacos", 1, false, true}, {"sushi", 0.5, false, true}},
+		},
+		{
+			[]string{"pizza", "tacos:2"},
+			nil,
+			[]Option{{"pizza", 1, false, false}, {"tacos", 2, false, true}},
+		},
+		{
+			[]string{"pizza:50%", "tacos:25%", "sushi:25 %"},
+			nil,
+			[]Option{{"pizza", 50, true, true}, {"tacos", 25, true, true}, {"sushi", 25, true, true}},
+		},
+		{
+			[]string{"pizza:50%", "tacos", "sushi"},
+			nil,
+			[]Option{{"pizza", 50, true, true}, {"tacos", 25, false, false}, {"sushi", 25, false, false}},
+		},
+		{[]string{"pizza:0", "tacos"}, &WeightError{"pizza:0"}, nil},
+		{[]string{"pizza:-2", "tacos"}, &WeightError{"pizza:-2"}, nil},
+		{
+			[]string{"7:30", "8:30", "7:30:2"},
+			nil,
+			[]Option{{"7:30", 1, false, false}, {"8:30", 1, false, false}, {"7:30", 2, false, true}},
+		},
+		{
+			[]string{"https://a.com", "<:pizza:123> pizza", "<:tacos:456>:3"},
+			nil,
+			[]Option{{"https://a.com", 1, false, false}, {"<:pizza:123> pizza", 1, false, false}, {"<:tacos:456>", 3, false, true}},
+		},
+		{
+			[]string{"pizza:lots", "tacos:", "sushi:NaN"},
+			nil,
+			[]Option{{"pizza:lots", 1, false, false}, {"tacos:", 1, false, false}, {"sushi:NaN", 1, false, false}},
+		},
+		{[]string{"pizza:0%", "tacos"}, &WeightError{"pizza:0%"}, nil},
+		{[]string{":3", "tacos"}, ErrEmptyOption, nil},
+		{[]string{"pizza:50%", "tacos:2"}, ErrMixedWeights, nil},
+		{[]string{"pizza:50%", "tacos:40%"}, ErrPercentTotal, nil},
+		{[]string{"pizza:80%", "tacos:40%"}, ErrPercentTotal, nil},
+		{[]string{"pizza:100%", "tacos"}, ErrPercentTotal, nil},
+		{[]string{}, ErrNoOptions, nil},
+	}
+
+	for _, test := range tests {
+		options, err := Parse(test.args)
+
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("Parse(%q) returned incorrect error.\nGot: %v\nWant: %v", test.args, err, test.err)
+		}
+		if !reflect.DeepEqual(options, test.expected) {
+			t.Errorf("Parse(%q) returned incorrect options.\nGot: %+v\nWant: %+v", test.args, options, test.expected)
+		}
+	}
+}
+
+func TestOdds(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []float64
+	}{
+		{[]string{"a", "b"}, []float64{0.5, 0.5}},
+		{[]string{"a:3", "b:1"}, []float64{0.75, 0.25}},
+		{[]string{"a:3", "b"}, []float64{0.75, 0.25}},
+		{[]string{"a:10%", "b", "c"}, []float64{0.1, 0.45, 0.45}},
+	}
+
+	for _, test := range tests {
+		options, err := Parse(test.args)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned unexpected error: %v", test.args, err)
+		}
+
+		odds := Odds(options)
+		for i := range odds {
+			if math.Abs(odds[i]-test.expected[i]) > 1e-9 {
+				t.Errorf("Odds(%q) = %v, want %v", test.args, odds, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestPick(t *testing.T) {
+	options, _ := Parse([]string{"a:1", "b:3"})
+
+	tests := []struct {
+		random   float64
+		expected int
+	}{
+		{0, 0},
+		{0.2499, 0},
+		{0.25, 1},
+		{0.9999, 1},
+	}
+
+	for _, test := range tests {
+		got := Pick(options, func() float64 { return test.random })
+		if got != test.expected {
+			t.Errorf("Pick with %v returned %d, want %d", test.random, got, test.expected)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	counts := make([]int, len(options))
+	for i := 0; i < 10000; i++ {
+		counts[Pick(options, r.Float64)]++
+	}
+	if counts[1] < 7000 || counts[1] > 8000 {
+		t.Errorf("Pick chose the option with 75%% odds %d times in 10000", counts[1])
+	}
+}
+
+func TestSample(t *testing.T) {
+	options, _ := Parse([]string{"a", "b", "c", "d", "e"})
+
+	tests := []struct {
+		n        int
+		random   float64
+		expected []int
+	}{
+		{3, 0, []int{0, 1, 2}},
+		{2, 0.99, []int{4, 3}},
+		{7, 0, []int{0, 1, 2, 3, 4}},
+		{0, 0, []int{}},
+	}
+
+	for _, test := range tests {
+		got := Sample(options, test.n, func() float64 { return test.random })
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Sample(%d) with %v returned %v, want %v", test.n, test.random, got, test.expected)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		got := Sample(options, len(options), r.Float64)
+		seen := make(map[int]bool)
+		for _, j := range got {
+			seen[j] = true
+		}
+		if len(got) != len(options) || len(seen) != len(options) {
+			t.Fatalf("Sample of every option returned %v, not each option once", got)
+		}
+	}
+
+	weighted, _ := Parse([]string{"a:1", "b:99"})
+	first := 0
+	for i := 0; i < 1000; i++ {
+		if Sample(weighted, 2, r.Float64)[0] == 1 {
+			first++
+		}
+	}
+	if first < 950 {
+		t.Errorf("Sample put the option with 99%% odds first %d times in 1000", first)
+	}
+}
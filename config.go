@@ -0,0 +1,194 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const defaultPrefix = "!"
+
+// GuildConfig holds the settings a guild has chosen for the bot
+type GuildConfig struct {
+	Prefix   string `json:"prefix,omitempty"`
+	Language string `json:"language,omitempty"`
+	// PinPolls pins each poll's message while it is open
+	PinPolls bool `json:"pin_polls,omitempty"`
+	// Reminders is where reminders to vote go, "dm" or "channel"
+	Reminders string `json:"reminders,omitempty"`
+	// TimeZone is the IANA name of the zone schedules run in
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// configStore caches guild settings in memory and persists them to disk
+type configStore struct {
+	mu     sync.RWMutex
+	file   string
+	guilds map[string]GuildConfig
+}
+
+var guildConfigs = &configStore{file: "guilds.json", guilds: make(map[string]GuildConfig)}
+
+// load reads the persisted guild settings
+func (c *configStore) load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return loadJSON(c.file, &c.guilds)
+}
+
+// Get returns the settings for guildID, filling in defaults
+func (c *configStore) Get(guildID string) GuildConfig {
+	c.mu.RLock()
+	cfg := c.guilds[guildID]
+	c.mu.RUnlock()
+
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultPrefix
+	}
+
+	return cfg
+}
+
+// Update applies f to the settings for guildID and persists the result
+func (c *configStore) Update(guildID string, f func(*GuildConfig) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cfg := c.guilds[guildID]
+	if err := f(&cfg); err != nil {
+		return err
+	}
+	c.guilds[guildID] = cfg
+
+	return saveJSON(c.file, c.guilds)
+}
+
+// configSetting is a single key that can be changed with the config command.
+// An empty value resets the setting to its default.
+type configSetting func(cfg *GuildConfig, value string) error
+
+var configSettings = map[string]configSetting{
+	"prefix": func(cfg *GuildConfig, value string) error {
+		if len(value) > 5 || strings.ContainsAny(value, " \n`") {
+			return newUserError("config.invalid_prefix")
+		}
+		cfg.Prefix = value
+		return nil
+	},
+	"language": func(cfg *GuildConfig, value string) error {
+		if value != "" && !catalog.Has(value) {
+			return newUserError("config.invalid_language", value, languageList())
+		}
+		cfg.Language = value
+		return nil
+	},
+	"pins": func(cfg *GuildConfig, value string) (err error) {
+		cfg.PinPolls, err = parseSwitch(value)
+		return err
+	},
+	"reminders": func(cfg *GuildConfig, value string) error {
+		value = strings.ToLower(value)
+		if value != "" && value != "dm" && value != "channel" {
+			return newUserError("config.invalid_reminders", value)
+		}
+		cfg.Reminders = value
+		return nil
+	},
+	"timezone": func(cfg *GuildConfig, value string) error {
+		if _, err := time.LoadLocation(value); err != nil || value == "Local" {
+			return newUserError("config.invalid_timezone", value)
+		}
+		cfg.TimeZone = value
+		return nil
+	},
+}
+
+// guildLocation returns the time zone of guildID, UTC unless it set one
+func guildLocation(guildID string) *time.Location {
+	if loc, err := time.LoadLocation(guildConfigs.Get(guildID).TimeZone); err == nil {
+		return loc
+	}
+
+	return time.UTC
+}
+
+// parseSwitch parses an on or off setting, an empty value is off
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes":
+		return true, nil
+	case "off", "false", "no", "":
+		return false, nil
+	}
+
+	return false, newUserError("config.invalid_switch", value)
+}
+
+// guildID returns the guild the channel belongs to, or "" for direct messages
+func guildID(s *discordgo.Session, channelID string) string {
+	c, err := channel(s, channelID)
+	if err != nil {
+		return ""
+	}
+
+	return c.GuildID
+}
+
+// channel returns channelID, from the state if it can
+func channel(s *discordgo.Session, channelID string) (*discordgo.Channel, error) {
+	if c, err := s.State.Channel(channelID); err == nil {
+		return c, nil
+	}
+
+	return s.Channel(channelID)
+}
+
+// GuildID returns the guild the command was invoked in, or "" in direct messages
+func (ctx *Context) GuildID() string {
+	return guildID(ctx.Session, ctx.Message.ChannelID)
+}
+
+// commandPrefixes returns every prefix a command in m may start with. The
+// guild's prefix comes first and a mention of the bot always works.
+func commandPrefixes(s *discordgo.Session, m *discordgo.MessageCreate) []string {
+	return []string{
+		guildConfigs.Get(guildID(s, m.ChannelID)).Prefix,
+		"<@" + botID + ">",
+		"<@!" + botID + ">",
+	}
+}
+
+func handleConfig(ctx *Context) error {
+	key := strings.ToLower(ctx.Args[0])
+	set, ok := configSettings[key]
+	if !ok {
+		keys := []string{}
+		for k := range configSettings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return ctx.Reply(ctx.T("config.unknown_setting", key, strings.Join(keys, ", ")))
+	}
+
+	gID := ctx.GuildID()
+	if gID == "" {
+		return ctx.Reply(ctx.T("config.guild_only"))
+	}
+
+	value := strings.TrimSpace(strings.TrimPrefix(ctx.Raw, ctx.Args[0]))
+	err := guildConfigs.Update(gID, func(cfg *GuildConfig) error {
+		return set(cfg, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	if value == "" {
+		return ctx.Reply(ctx.T("config.reset", key))
+	}
+	return ctx.Reply(ctx.T("config.set", key, value))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/throttle"
+)
+
+// Cooldown limits how often a command may be used by one user and in one channel
+type Cooldown struct {
+	User    throttle.Rate
+	Channel throttle.Rate
+}
+
+// defaultCooldown applies to commands that don't set their own
+var defaultCooldown = Cooldown{
+	User:    throttle.Rate{Burst: 3, Every: 5 * time.Second},
+	Channel: throttle.Rate{Burst: 10, Every: 2 * time.Second},
+}
+
+// slowDownRate limits the "slow down" replies so they can't be used for spam either
+var slowDownRate = throttle.Rate{Burst: 1, Every: 30 * time.Second}
+
+// cooldowns holds the token buckets for every command
+type cooldowns struct {
+	mu       sync.Mutex
+	limiters map[*Command][2]*throttle.Limiter
+	slowDown *throttle.Limiter
+}
+
+func newCooldowns() *cooldowns {
+	return &cooldowns{
+		limiters: make(map[*Command][2]*throttle.Limiter),
+		slowDown: throttle.NewLimiter(slowDownRate),
+	}
+}
+
+// allow takes a token for c from both user's and channel's buckets, or from
+// neither when either is empty, returning how long until both have one
+func (cd *cooldowns) allow(c *Command, user, channel string, now time.Time) (bool, time.Duration) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	l, ok := cd.limiters[c]
+	if !ok {
+		rate := defaultCooldown
+		if c.Cooldown != nil {
+			rate = *c.Cooldown
+		}
+		l = [2]*throttle.Limiter{throttle.NewLimiter(rate.User), throttle.NewLimiter(rate.Channel)}
+		cd.limiters[c] = l
+	}
+
+	wait := l[0].Wait(user, now)
+	if w := l[1].Wait(channel, now); w > wait {
+		wait = w
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	l[0].Allow(user, now)
+	l[1].Allow(channel, now)
+	return true, 0
+}
+
+// middleware drops commands that exceed their cooldown, telling the user to
+// slow down at most once per slowDownRate
+func (cd *cooldowns) middleware(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) error {
+		now := time.Now()
+		user, channel := ctx.Message.Author.ID, ctx.Message.ChannelID
+
+		if ok, wait := cd.allow(ctx.Command, user, channel, now); !ok {
+			fmt.Printf("throttled %s from %s in %s\n", ctx.Command.Name, user, channel)
+
+			// replying while discord is already limiting us would only queue more requests
+			if rateLimits.Wait(channel, now) > 0 {
+				return nil
+			}
+			if ok, _ := cd.slowDown.Allow(user+"/"+channel, now); ok {
+				wait = wait.Round(time.Second) + time.Second
+				return ctx.Reply(ctx.Tn("slow_down", int(wait/time.Second), ctx.Prefix+ctx.Command.Name))
+			}
+			return nil
+		}
+
+		return next(ctx)
+	}
+}
+
+// messagesURLPattern matches the URLs for sending and editing a channel's
+// messages, which share a rate limit
+var messagesURLPattern = regexp.MustCompile(`/channels/(\d+)/messages(?:/\d+)?$`)
+
+// channelLimits remembers how long discord told the bot to stop sending or
+// editing messages in each channel. It learns this from discordgo's RateLimit
+// events instead of its buckets, which stay locked while their requests are
+// in flight.
+type channelLimits struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+var rateLimits = &channelLimits{until: make(map[string]time.Time)}
+
+// handleRateLimit records a rate limit discord hit a channel's messages with
+func handleRateLimit(s *discordgo.Session, r *discordgo.RateLimit) {
+	m := messagesURLPattern.FindStringSubmatch(strings.SplitN(r.URL, "?", 2)[0])
+	if m == nil {
+		return
+	}
+
+	rateLimits.limit(m[1], time.Now().Add(r.RetryAfter*time.Millisecond))
+}
+
+// limit holds channelID's messages back until until
+func (cl *channelLimits) limit(channelID string, until time.Time) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if until.After(cl.until[channelID]) {
+		cl.until[channelID] = until
+	}
+}
+
+// Wait returns how long discord is holding channelID's messages back from now
+func (cl *channelLimits) Wait(channelID string, now time.Time) time.Duration {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	until, ok := cl.until[channelID]
+	if !ok {
+		return 0
+	}
+	if !until.After(now) {
+		delete(cl.until, channelID)
+		return 0
+	}
+
+	return until.Sub(now)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mroseman95/discord-poll-bot/throttle"
+)
+
+func TestCooldownAllow(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &Command{Name: "poll", Cooldown: &Cooldown{
+		User:    throttle.Rate{Burst: 2, Every: time.Second},
+		Channel: throttle.Rate{Burst: 1, Every: time.Second},
+	}}
+
+	calls := []struct {
+		user, channel string
+		ok            bool
+		wait          time.Duration
+	}{
+		{"alice", "general", true, 0},
+		// the channel is out of tokens, which mustn't cost alice one
+		{"alice", "general", false, time.Second},
+		{"alice", "random", true, 0},
+		{"alice", "memes", false, time.Second},
+		{"bob", "memes", true, 0},
+	}
+
+	cd := newCooldowns()
+	for i, call := range calls {
+		ok, wait := cd.allow(c, call.user, call.channel, start)
+
+		if ok != call.ok || wait != call.wait {
+			t.Errorf("call %d: allow returned (%v, %v)\nWant: (%v, %v)", i, ok, wait, call.ok, call.wait)
+		}
+	}
+}
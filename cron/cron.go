@@ -0,0 +1,171 @@
+// Package cron parses five field cron expressions and works out when they
+// next fire
+package cron
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is returned for an expression that isn't valid cron
+var ErrInvalid = errors.New("invalid cron expression")
+
+// maxSearch bounds how far ahead Next looks for a time that matches, so
+// expressions that never fire, like the 31st of February, don't loop forever
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// field is the range of one of the five fields, and the names it accepts
+type field struct {
+	min, max int
+	names    []string
+}
+
+var fields = []field{
+	{0, 59, nil},
+	{0, 23, nil},
+	{1, 31, nil},
+	{1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 0 and 7 are both sunday
+	{0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record a * day of month or week, since a day only
+	// has to match both when neither is
+	domStar, dowStar bool
+}
+
+// Parse reads a standard five field cron expression: minute, hour, day of
+// month, month and day of week. Fields take *, numbers, names like mon or jan,
+// ranges, lists and steps like */15.
+func Parse(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, ErrInvalid
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		b, err := parseField(strings.ToLower(part), fields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: parts[2] == "*",
+		dowStar: parts[4] == "*",
+	}, nil
+}
+
+// parseField returns the values part allows as a bit set
+func parseField(part string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n < 1 {
+				return 0, ErrInvalid
+			}
+			step, item = n, item[:i]
+		}
+
+		lo, hi := f.min, f.max
+		if item != "*" {
+			var err error
+			bounds := strings.SplitN(item, "-", 2)
+			if lo, err = parseValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = parseValue(bounds[1], f); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// 5/15 runs from 5 to the end of the range
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, ErrInvalid
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// parseValue reads a number or one of f's names
+func parseValue(s string, f field) (int, error) {
+	for i, name := range f.names {
+		if s == name {
+			return f.min + i, nil
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+
+	return n, nil
+}
+
+// has reports whether bits contains v
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+// matchDay reports whether the schedule runs on t's day
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// Next returns the first time after t the schedule fires, in t's location, or
+// the zero time if it never does
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	end := t.Add(maxSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(end) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(s.minute, t.Minute()):
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,115 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{"* * * * *", true},
+		{"0 18 * * fri", true},
+		{"*/15 9-17 * * mon-fri", true},
+		{"0 0 1,15 * *", true},
+		{"30 12 * jan-mar 0,7", true},
+		{"5/10 * * * *", true},
+		{"* * * *", false},
+		{"* * * * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"*/0 * * * *", false},
+		{"5-1 * * * *", false},
+		{"* * * * funday", false},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.expr)
+		if test.ok && err != nil {
+			t.Errorf("Parse(%q) returned unexpected error: %v", test.expr, err)
+		}
+		if !test.ok && err != ErrInvalid {
+			t.Errorf("Parse(%q) returned incorrect error.\nGot: %v\nWant: %v", test.expr, err, ErrInvalid)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	berlin := time.FixedZone("CET", 60*60)
+
+	tests := []struct {
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			"* * * * *",
+			time.Date(2030, 1, 1, 12, 0, 30, 0, time.UTC),
+			time.Date(2030, 1, 1, 12, 1, 0, 0, time.UTC),
+		},
+		{
+			// the first of january 2030 is a tuesday
+			"0 18 * * fri",
+			time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC),
+			time.Date(2030, 1, 4, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			"0 18 * * fri",
+			time.Date(2030, 1, 4, 18, 0, 0, 0, time.UTC),
+			time.Date(2030, 1, 11, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			"*/15 9-17 * * mon-fri",
+			time.Date(2030, 1, 4, 17, 50, 0, 0, time.UTC),
+			time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			"0 0 29 2 *",
+			time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2032, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// either the day of the month or the week matches
+			"0 0 15 * sun",
+			time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2030, 1, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"0 0 * * 7",
+			time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2030, 1, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"0 9 * * *",
+			time.Date(2030, 1, 1, 8, 30, 0, 0, time.UTC),
+			time.Date(2030, 1, 2, 9, 0, 0, 0, berlin),
+		},
+		{
+			"0 0 31 2 *",
+			time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Time{},
+		},
+	}
+
+	for _, test := range tests {
+		s, err := Parse(test.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned unexpected error: %v", test.expr, err)
+		}
+
+		from := test.from
+		if test.expected.Location() == berlin {
+			from = from.In(berlin)
+		}
+
+		if got := s.Next(from); !got.Equal(test.expected) {
+			t.Errorf("Next(%q) from %v returned incorrect time.\nGot: %v\nWant: %v",
+				test.expr, from, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// deadlineLayouts are the absolute times --closes accepts, read in the guild's
+// time zone unless they carry their own offset
+var deadlineLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseDeadline reads value as a duration from now, like 48h, or as an
+// absolute time in loc, like 2006-01-02 15:04
+func parseDeadline(value string, now time.Time, loc *time.Location) (time.Time, bool) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), d > 0
+	}
+
+	for _, layout := range deadlineLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), loc); err == nil {
+			return t, t.After(now)
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// deadlineTimers closes polls when their deadline passes
+type deadlineTimers struct {
+	mu     sync.Mutex
+	timers map[int]*time.Timer
+}
+
+var deadlines = &deadlineTimers{timers: make(map[int]*time.Timer)}
+
+// Schedule closes poll id at closesAt, replacing any deadline it already had
+func (d *deadlineTimers) Schedule(s *discordgo.Session, id int, closesAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, ok := d.timers[id]; ok {
+		t.Stop()
+	}
+
+	d.timers[id] = time.AfterFunc(time.Until(closesAt), func() {
+		d.mu.Lock()
+		delete(d.timers, id)
+		d.mu.Unlock()
+
+		err := closePoll(s, id)
+		if _, closed := err.(*userError); err != nil && !closed {
+			fmt.Printf("failed to close poll %d at its deadline: %v\n", id, err)
+		}
+	})
+}
+
+// Cancel forgets the deadline of poll id
+func (d *deadlineTimers) Cancel(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, ok := d.timers[id]; ok {
+		t.Stop()
+		delete(d.timers, id)
+	}
+}
+
+// scheduleDeadlines sets the timers of every open poll with a deadline, closing
+// straight away the ones whose deadline passed while the bot was offline
+func scheduleDeadlines(s *discordgo.Session) {
+	due := map[int]time.Time{}
+	polls.Each(func(e *pollEntry) {
+		if !e.Closed && !e.ClosesAt.IsZero() {
+			due[e.ID] = e.ClosesAt
+		}
+	})
+
+	for id, closesAt := range due {
+		deadlines.Schedule(s, id, closesAt)
+	}
+}
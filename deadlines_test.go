@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	utc2 := time.FixedZone("UTC+2", 2*60*60)
+
+	tests := []struct {
+		value    string
+		loc      *time.Location
+		ok       bool
+		expected time.Time
+	}{
+		{"48h", time.UTC, true, now.Add(48 * time.Hour)},
+		{"90m", time.UTC, true, now.Add(90 * time.Minute)},
+		{"0s", time.UTC, false, now},
+		{"-1h", time.UTC, false, now.Add(-time.Hour)},
+		{"2018-06-02T09:30:00+02:00", time.UTC, true, time.Date(2018, 6, 2, 7, 30, 0, 0, time.UTC)},
+		{"2018-06-02T09:30", time.UTC, true, time.Date(2018, 6, 2, 9, 30, 0, 0, time.UTC)},
+		{"2018-06-02 09:30", time.UTC, true, time.Date(2018, 6, 2, 9, 30, 0, 0, time.UTC)},
+		{" 2018-06-02 ", time.UTC, true, time.Date(2018, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{"2018-06-01 11:00", time.UTC, false, time.Date(2018, 6, 1, 11, 0, 0, 0, time.UTC)},
+		// times without an offset are in the guild's time zone
+		{"2018-06-02 09:30", utc2, true, time.Date(2018, 6, 2, 7, 30, 0, 0, time.UTC)},
+		{"2018-06-02T09:30:00Z", utc2, true, time.Date(2018, 6, 2, 9, 30, 0, 0, time.UTC)},
+		{"2018-06-01 13:30", utc2, false, time.Date(2018, 6, 1, 11, 30, 0, 0, time.UTC)},
+		{"48h", utc2, true, now.Add(48 * time.Hour)},
+		{"tomorrow", time.UTC, false, time.Time{}},
+		{"", time.UTC, false, time.Time{}},
+	}
+
+	for _, test := range tests {
+		got, ok := parseDeadline(test.value, now, test.loc)
+
+		if ok != test.ok || !got.Equal(test.expected) {
+			t.Errorf("parseDeadline(%q) returned (%v, %v)\nWant: (%v, %v)", test.value, got, ok, test.expected, test.ok)
+		}
+	}
+}
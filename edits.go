@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+var (
+	voidPattern   = regexp.MustCompile(`(?:^|\s)--void\b`)
+	moveToPattern = regexp.MustCompile(`(?:^|\s)--move-to=("[^"]*"|\S+)`)
+)
+
+// maxEditHistory is how many edits of a poll are listed at once
+const maxEditHistory = 20
+
+// pollEdit is one change made to a poll while it was open
+type pollEdit struct {
+	At       time.Time `json:"at"`
+	EditorID string    `json:"editor_id"`
+	// Action is "question", "rename", "add" or "remove"
+	Action string `json:"action"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	// Votes is how many votes a removed option had, voided unless MovedTo
+	// names the option they went to
+	Votes   int    `json:"votes,omitempty"`
+	MovedTo string `json:"moved_to,omitempty"`
+}
+
+// reactionsFor returns the emoji voting for option i: its own, then those of
+// removed options whose votes moved to it
+func (e *pollEntry) reactionsFor(i int) []string {
+	emoji := []string{e.Emoji[i]}
+	for em, o := range e.MovedEmoji {
+		if o == e.Poll.Options[i] {
+			emoji = append(emoji, em)
+		}
+	}
+	sort.Strings(emoji[1:])
+
+	return emoji
+}
+
+// findOption returns the index of the option ref names, by its text, its
+// emoji or its number
+func (e *pollEntry) findOption(ref string) (int, bool) {
+	ref = strings.TrimSpace(ref)
+	emoji, _ := splitEmoji(ref)
+	for i, o := range e.Poll.Options {
+		if strings.EqualFold(o, ref) || e.Emoji[i] == ref || emoji != "" && e.Emoji[i] == emoji {
+			return i, true
+		}
+	}
+
+	if n, err := strconv.Atoi(strings.TrimPrefix(ref, "#")); err == nil && n >= 1 && n <= len(e.Poll.Options) {
+		return n - 1, true
+	}
+
+	return 0, false
+}
+
+// usedEmoji returns every emoji that votes on e, including moved ones
+func (e *pollEntry) usedEmoji() map[string]bool {
+	used := make(map[string]bool)
+	for _, em := range e.Emoji {
+		used[em] = true
+	}
+	for em := range e.MovedEmoji {
+		used[em] = true
+	}
+
+	return used
+}
+
+// editSubcommands are the changes `poll edit <id>` makes
+var editSubcommands = map[string]func(ctx *Context, e *pollEntry, rest string) (*pollEdit, error){
+	"question": editQuestion,
+	"rename":   editRename,
+	"add":      editAdd,
+	"remove":   editRemove,
+}
+
+// handlePollEdit changes the question or options of an open poll, or lists
+// the changes made so far when given no change
+func handlePollEdit(ctx *Context) error {
+	id, err := pollArg(ctx)
+	if err != nil {
+		return err
+	}
+	if len(ctx.Args) == 1 {
+		return showEditHistory(ctx, id)
+	}
+
+	edit, ok := editSubcommands[strings.ToLower(ctx.Args[1])]
+	if !ok {
+		return &UsageError{ctx.Command}
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(ctx.Raw, ctx.Args[0]))
+	rest = strings.TrimSpace(rest[len(ctx.Args[1]):])
+	if err := checkManagePoll(ctx, id); err != nil {
+		return err
+	}
+
+	var before, after pollEntry
+	var change *pollEdit
+	err = polls.Update(id, func(e *pollEntry) error {
+		var err error
+		if e.Closed {
+			return newUserError("poll.already_closed", id)
+		}
+
+		before = e.snapshot()
+		if change, err = edit(ctx, e, rest); err != nil {
+			return err
+		}
+		change.At, change.EditorID = time.Now(), ctx.Message.Author.ID
+		e.Edits = append(e.Edits, *change)
+		after = e.snapshot()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	updateReactions(ctx.Session, &before, &after)
+	refresher.Refresh(ctx.Session, id)
+	if change.Action == "remove" {
+		notifyRemoved(ctx.Session, &before, &after, change)
+	}
+
+	fmt.Printf("edited poll %d: %s\n", id, change.Action)
+	return ctx.Reply(ctx.T("edit.done", id))
+}
+
+func editQuestion(ctx *Context, e *pollEntry, rest string) (*pollEdit, error) {
+	question := strings.TrimSpace(strings.Trim(rest, `"`))
+	if question == "" {
+		return nil, &UsageError{ctx.Command}
+	}
+
+	change := &pollEdit{Action: "question", From: e.Question, To: question}
+	e.Question = question
+	return change, nil
+}
+
+func editRename(ctx *Context, e *pollEntry, rest string) (*pollEdit, error) {
+	args := splitArgs(rest, ",")
+	if len(args) != 2 {
+		return nil, &UsageError{ctx.Command}
+	}
+	// role polls name their options after the roles they give
+	if e.Roles != nil {
+		return nil, newUserError("edit.roles")
+	}
+
+	i, ok := e.findOption(args[0])
+	if !ok {
+		return nil, newUserError("edit.unknown_option", args[0])
+	}
+	from := e.Poll.Options[i]
+	if contains(e.Poll.Options, args[1]) {
+		return nil, editError(poll.ErrDuplicateOption, args[1])
+	}
+	e.moveSources(from, args[1])
+	if err := e.Poll.RenameOption(from, args[1]); err != nil {
+		return nil, editError(err, args[1])
+	}
+	for em, o := range e.MovedEmoji {
+		if o == from {
+			e.MovedEmoji[em] = args[1]
+		}
+	}
+
+	return &pollEdit{Action: "rename", From: from, To: args[1]}, nil
+}
+
+func editAdd(ctx *Context, e *pollEntry, rest string) (*pollEdit, error) {
+	if rest == "" {
+		return nil, &UsageError{ctx.Command}
+	}
+	if err := canChangeOptions(e); err != nil {
+		return nil, err
+	}
+
+	emoji, option := splitEmoji(rest)
+	if option == "" {
+		return nil, &UsageError{ctx.Command}
+	}
+	if len(e.Poll.Options) >= maxReactions {
+		return nil, newUserError("poll.too_many_options", maxReactions)
+	}
+
+	used := e.usedEmoji()
+	if emoji == "" {
+		for _, n := range numberEmoji {
+			if !used[n] {
+				emoji = n
+				break
+			}
+		}
+		if emoji == "" {
+			return nil, newUserError("poll.too_many_options", len(numberEmoji))
+		}
+	} else if used[emoji] {
+		return nil, newUserError("poll.duplicate_emoji", emojiText(emoji))
+	}
+
+	if err := e.Poll.AddOption(option); err != nil {
+		return nil, editError(err, option)
+	}
+	e.Emoji = append(e.Emoji, emoji)
+
+	return &pollEdit{Action: "add", To: option}, nil
+}
+
+func editRemove(ctx *Context, e *pollEntry, rest string) (*pollEdit, error) {
+	void := voidPattern.MatchString(rest)
+	moveTo := ""
+	if m := moveToPattern.FindStringSubmatch(rest); m != nil {
+		moveTo = strings.Trim(m[1], `"`)
+	}
+	rest = strings.TrimSpace(moveToPattern.ReplaceAllString(voidPattern.ReplaceAllString(rest, ""), ""))
+	if rest == "" || void && moveTo != "" {
+		return nil, &UsageError{ctx.Command}
+	}
+	if err := canChangeOptions(e); err != nil {
+		return nil, err
+	}
+
+	i, ok := e.findOption(rest)
+	if !ok {
+		return nil, newUserError("edit.unknown_option", rest)
+	}
+	option, emoji := e.Poll.Options[i], e.Emoji[i]
+	votes := e.Poll.Count(option)
+
+	// dropping votes is never the default, whoever removes an option someone
+	// voted for has to say what happens to the votes
+	if votes > 0 && !void && moveTo == "" {
+		return nil, newUserError("edit.policy", option, votes)
+	}
+
+	if moveTo != "" {
+		j, ok := e.findOption(moveTo)
+		if !ok {
+			return nil, newUserError("edit.unknown_option", moveTo)
+		}
+		if j == i {
+			return nil, &UsageError{ctx.Command}
+		}
+		moveTo = e.Poll.Options[j]
+	}
+
+	// check the removal can go ahead before moving the sources of its votes
+	if _, err := e.Poll.Copy().RemoveOption(option, moveTo); err != nil {
+		return nil, editError(err, option)
+	}
+	e.moveSources(option, moveTo)
+	e.Poll.RemoveOption(option, moveTo)
+	e.Emoji = append(e.Emoji[:i:i], e.Emoji[i+1:]...)
+
+	// reactions for the removed option now stand for the option its votes
+	// moved to, so taking one back still takes back the vote
+	for em, o := range e.MovedEmoji {
+		if o == option {
+			delete(e.MovedEmoji, em)
+			if moveTo != "" {
+				e.MovedEmoji[em] = moveTo
+			}
+		}
+	}
+	if moveTo != "" && votes > 0 {
+		if e.MovedEmoji == nil {
+			e.MovedEmoji = make(map[string]string)
+		}
+		e.MovedEmoji[emoji] = moveTo
+	}
+
+	return &pollEdit{Action: "remove", From: option, Votes: votes, MovedTo: moveTo}, nil
+}
+
+// canChangeOptions returns a userError if options can't be added to or
+// removed from poll e
+func canChangeOptions(e *pollEntry) error {
+	if e.Roles != nil {
+		return newUserError("edit.roles")
+	}
+	// ballots that are already out refer to the options by number
+	if e.Secret {
+		return newUserError("edit.secret")
+	}
+
+	return nil
+}
+
+// editError turns the errors of changing a poll's options into userErrors
+func editError(err error, option string) error {
+	switch err {
+	case poll.ErrDuplicateOption:
+		return newUserError("edit.duplicate_option", option)
+	case poll.ErrTooFewOptions:
+		return newUserError("edit.too_few_options")
+	case poll.ErrAnonymous:
+		return newUserError("edit.anonymous")
+	}
+
+	return err
+}
+
+// updateReactions adds and removes the bot's reactions on every message of a
+// poll for the options added or removed between before and after
+func updateReactions(s *discordgo.Session, before, after *pollEntry) {
+	if after.Secret {
+		return
+	}
+
+	for _, msg := range after.messages() {
+		for _, em := range after.Emoji {
+			if !contains(before.Emoji, em) {
+				if err := s.MessageReactionAdd(msg.ChannelID, msg.MessageID, em); err != nil {
+					fmt.Printf("failed to add %s to poll %d: %v\n", em, after.ID, err)
+				}
+			}
+		}
+		for _, em := range before.Emoji {
+			if !contains(after.Emoji, em) {
+				if err := s.MessageReactionRemove(msg.ChannelID, msg.MessageID, em, "@me"); err != nil {
+					fmt.Printf("failed to remove %s from poll %d: %v\n", em, after.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// notifyRemoved tells the members who voted for an option removed from a poll
+// what became of their vote
+func notifyRemoved(s *discordgo.Session, before, after *pollEntry, change *pollEdit) {
+	for _, v := range before.Poll.Votes[change.From] {
+		lang := language(after.GuildID, v.Voter)
+		text := catalog.T(lang, "edit.voided", change.From, after.ID)
+		if change.MovedTo != "" {
+			text = catalog.T(lang, "edit.moved", change.From, after.ID, change.MovedTo)
+		}
+
+		embed := &discordgo.MessageEmbed{
+			Title:       after.Question,
+			URL:         fmt.Sprintf("https://discordapp.com/channels/%s/%s/%s", after.GuildID, after.ChannelID, after.MessageID),
+			Description: text,
+			Color:       pollColor,
+		}
+		if embed.Title == "" {
+			embed.Title = catalog.T(lang, "poll.untitled")
+		}
+
+		if err := sendDM(s, v.Voter, embed); err != nil {
+			fmt.Printf("failed to tell a voter about an edit of poll %d: %v\n", after.ID, err)
+		}
+	}
+}
+
+// showEditHistory lists the latest changes made to poll id
+func showEditHistory(ctx *Context, id int) error {
+	var e pollEntry
+	found := polls.View(id, func(entry *pollEntry) { e = entry.snapshot() })
+	if !found || !e.inGuild(ctx.GuildID()) {
+		return newUserError("poll.not_found", id)
+	}
+	if len(e.Edits) == 0 {
+		return ctx.Reply(ctx.T("edit.none", id))
+	}
+
+	edits := e.Edits
+	if len(edits) > maxEditHistory {
+		edits = edits[len(edits)-maxEditHistory:]
+	}
+
+	loc := guildLocation(ctx.GuildID())
+	lines := []string{ctx.T("edit.history", id)}
+	for _, edit := range edits {
+		var what string
+		switch {
+		case edit.Action == "remove" && edit.MovedTo != "":
+			what = ctx.Tn("edit.history_moved", edit.Votes, edit.From, edit.MovedTo)
+		case edit.Action == "remove":
+			what = ctx.Tn("edit.history_voided", edit.Votes, edit.From)
+		default:
+			what = ctx.T("edit.history_"+edit.Action, edit.From, edit.To)
+		}
+		lines = append(lines, fmt.Sprintf("`%s` <@%s> %s", edit.At.In(loc).Format(scheduleTimeLayout), edit.EditorID, what))
+	}
+
+	for _, msg := range joinLimited(lines, "\n", maxMessageLength) {
+		if err := ctx.Reply(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+// editTestEntry returns a poll where alice voted maybe and bob voted yes
+func editTestEntry(options ...string) *pollEntry {
+	p, _ := poll.NewPoll(options)
+	e := &pollEntry{Emoji: append([]string(nil), numberEmoji[:len(options)]...), Poll: p}
+	e.reactionVote(voteSource{"c1", "m1", numberEmoji[0]}, "bob")
+	if len(options) > 2 {
+		e.reactionVote(voteSource{"c1", "m1", numberEmoji[2]}, "alice")
+	}
+
+	return e
+}
+
+func TestEditRemove(t *testing.T) {
+	ctx := &Context{Command: &Command{Name: "poll"}}
+	yes, no, maybe := numberEmoji[0], numberEmoji[1], numberEmoji[2]
+
+	tests := []struct {
+		options  []string
+		rest     string
+		err      error
+		edit     *pollEdit
+		emoji    []string
+		counts   map[string]int
+		moved    map[string]string
+		aliceSrc voteSource
+	}{
+		// dropping votes has to be asked for
+		{
+			[]string{"yes", "no", "maybe"}, "maybe",
+			newUserError("edit.policy", "maybe", 1), nil,
+			nil, nil, nil, voteSource{},
+		},
+		{
+			[]string{"yes", "no", "maybe"}, "maybe --void",
+			nil, &pollEdit{Action: "remove", From: "maybe", Votes: 1},
+			[]string{yes, no}, map[string]int{"yes": 1, "no": 0}, nil, voteSource{},
+		},
+		{
+			[]string{"yes", "no", "maybe"}, "#3 --move-to=yes",
+			nil, &pollEdit{Action: "remove", From: "maybe", Votes: 1, MovedTo: "yes"},
+			[]string{yes, no}, map[string]int{"yes": 2, "no": 0}, map[string]string{maybe: "yes"},
+			voteSource{"c1", "m1", maybe},
+		},
+		// an option nobody voted for goes without a policy
+		{
+			[]string{"yes", "no", "maybe"}, "no",
+			nil, &pollEdit{Action: "remove", From: "no"},
+			[]string{yes, maybe}, map[string]int{"yes": 1, "maybe": 1}, nil, voteSource{"c1", "m1", maybe},
+		},
+		{
+			[]string{"yes", "no", "maybe"}, "maybe --void --move-to=yes",
+			&UsageError{ctx.Command}, nil,
+			nil, nil, nil, voteSource{},
+		},
+		{
+			[]string{"yes", "no", "maybe"}, "maybe --move-to=maybe",
+			&UsageError{ctx.Command}, nil,
+			nil, nil, nil, voteSource{},
+		},
+		{
+			[]string{"yes", "no", "maybe"}, "maybe --move-to=nope",
+			newUserError("edit.unknown_option", "nope"), nil,
+			nil, nil, nil, voteSource{},
+		},
+		{
+			[]string{"yes", "no", "maybe"}, "nope --void",
+			newUserError("edit.unknown_option", "nope"), nil,
+			nil, nil, nil, voteSource{},
+		},
+		{
+			[]string{"yes", "no"}, "no",
+			newUserError("edit.too_few_options"), nil,
+			nil, nil, nil, voteSource{},
+		},
+	}
+
+	for _, test := range tests {
+		e := editTestEntry(test.options...)
+		before := e.snapshot()
+
+		edit, err := editRemove(ctx, e, test.rest)
+
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("editRemove(%q) returned incorrect error.\nGot: %v\nWant: %v", test.rest, err, test.err)
+		}
+		if !reflect.DeepEqual(edit, test.edit) {
+			t.Errorf("editRemove(%q) returned incorrect edit.\nGot: %+v\nWant: %+v", test.rest, edit, test.edit)
+		}
+		if err != nil {
+			// a refused removal leaves the poll as it was
+			if !reflect.DeepEqual(*e, before) {
+				t.Errorf("editRemove(%q) changed the poll although it failed", test.rest)
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(e.Emoji, test.emoji) {
+			t.Errorf("editRemove(%q) left emoji %q\nWant: %q", test.rest, e.Emoji, test.emoji)
+		}
+		counts := make(map[string]int)
+		for _, o := range e.Poll.Options {
+			counts[o] = e.Poll.Count(o)
+		}
+		if !reflect.DeepEqual(counts, test.counts) {
+			t.Errorf("editRemove(%q) left counts %v\nWant: %v", test.rest, counts, test.counts)
+		}
+		if !reflect.DeepEqual(e.MovedEmoji, test.moved) {
+			t.Errorf("editRemove(%q) left moved emoji %v\nWant: %v", test.rest, e.MovedEmoji, test.moved)
+		}
+
+		// alice's vote is still taken back by the reaction she cast it with
+		var src voteSource
+		for _, o := range e.Poll.Choices("alice") {
+			src = e.Sources[sourceKey(o, "alice")]
+		}
+		if src != test.aliceSrc {
+			t.Errorf("editRemove(%q) left alice's vote with source %+v\nWant: %+v", test.rest, src, test.aliceSrc)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// discordEpoch is the time discord snowflakes count from, in milliseconds
+const discordEpoch = 1420070400000
+
+const day = 24 * time.Hour
+
+// Eligibility is the rules a member has to meet to vote on a poll
+type Eligibility struct {
+	// RequiredRoles all have to be held, ExcludedRoles none of them
+	RequiredRoles []string      `json:"required_roles,omitempty"`
+	ExcludedRoles []string      `json:"excluded_roles,omitempty"`
+	MinMemberAge  time.Duration `json:"min_member_age,omitempty"`
+	MinAccountAge time.Duration `json:"min_account_age,omitempty"`
+}
+
+// empty reports whether the rules let everyone vote
+func (r *Eligibility) empty() bool {
+	return r == nil || len(r.RequiredRoles) == 0 && len(r.ExcludedRoles) == 0 &&
+		r.MinMemberAge == 0 && r.MinAccountAge == 0
+}
+
+// IneligibleError explains which rule kept a member from voting
+type IneligibleError struct {
+	// Rule is "required_role", "excluded_role", "member_age", "account_age",
+	// "channel" or "role_full"
+	Rule string
+	// RoleID is the role the rule is about, if any
+	RoleID string
+	// Age is the age the member had to reach
+	Age time.Duration
+}
+
+func (err *IneligibleError) Error() string {
+	return "ineligible to vote: " + err.Rule
+}
+
+// explain describes err to the member it kept from voting on a poll in guildID
+func (err *IneligibleError) explain(s *discordgo.Session, guildID, lang string) string {
+	switch err.Rule {
+	case "required_role", "excluded_role", "role_full":
+		name := err.RoleID
+		if role, rerr := s.State.Role(guildID, err.RoleID); rerr == nil {
+			name = role.Name
+		}
+		return catalog.T(lang, "eligibility."+err.Rule, name)
+	case "member_age", "account_age":
+		return catalog.Plural(lang, "eligibility."+err.Rule, int(err.Age/day))
+	}
+
+	return catalog.T(lang, "eligibility."+err.Rule)
+}
+
+// check returns an *IneligibleError if m doesn't meet the rules at now
+func (r *Eligibility) check(m *discordgo.Member, now time.Time) error {
+	if r.empty() {
+		return nil
+	}
+
+	for _, role := range r.RequiredRoles {
+		if !hasRole(m, role) {
+			return &IneligibleError{Rule: "required_role", RoleID: role}
+		}
+	}
+	for _, role := range r.ExcludedRoles {
+		if hasRole(m, role) {
+			return &IneligibleError{Rule: "excluded_role", RoleID: role}
+		}
+	}
+
+	if r.MinMemberAge > 0 {
+		joined, err := discordgo.Timestamp(m.JoinedAt).Parse()
+		if err != nil || now.Sub(joined) < r.MinMemberAge {
+			return &IneligibleError{Rule: "member_age", Age: r.MinMemberAge}
+		}
+	}
+	if r.MinAccountAge > 0 {
+		created, err := snowflakeTime(m.User.ID)
+		if err != nil || now.Sub(created) < r.MinAccountAge {
+			return &IneligibleError{Rule: "account_age", Age: r.MinAccountAge}
+		}
+	}
+
+	return nil
+}
+
+// snowflakeTime returns when the discord ID id was created
+func snowflakeTime(id string) (time.Time, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ms := n>>22 + discordEpoch
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond)), nil
+}
+
+// parseAge reads an age in days or weeks, like 30d or 2w
+func parseAge(value string) (time.Duration, bool) {
+	units := map[string]time.Duration{"d": day, "w": 7 * day}
+	for suffix, unit := range units {
+		if !strings.HasSuffix(value, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(value, suffix))
+		return time.Duration(n) * unit, err == nil && n > 0
+	}
+
+	return 0, false
+}
+
+// parseRoles reads a comma separated list of role mentions or IDs
+func parseRoles(value string) ([]string, bool) {
+	roles := []string{}
+	for _, v := range splitArgs(value, ",") {
+		role, ok := parseRole(v)
+		if !ok {
+			return nil, false
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, len(roles) > 0
+}
+
+// member returns userID's membership of guildID, from the state if it can
+func member(s *discordgo.Session, guildID, userID string) (*discordgo.Member, error) {
+	if m, err := s.State.Member(guildID, userID); err == nil {
+		return m, nil
+	}
+
+	m, err := s.GuildMember(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	m.GuildID = guildID
+	s.State.MemberAdd(m)
+
+	return m, nil
+}
+
+// checkVoter returns an *IneligibleError if userID may not vote on poll e
+// under its rules, looking up their membership as it stands now
+func checkVoter(s *discordgo.Session, e *pollEntry, userID string) error {
+	if e.Rules.empty() && !e.Secret {
+		return nil
+	}
+
+	// ballots arrive by DM, so make sure the voter can still see the poll
+	if e.Secret {
+		perms, err := s.State.UserChannelPermissions(userID, e.ChannelID)
+		if err != nil {
+			perms, err = s.UserChannelPermissions(userID, e.ChannelID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check %s can see poll %d: %v", userID, e.ID, err)
+		}
+		if perms&(discordgo.PermissionReadMessages|discordgo.PermissionAdministrator) == 0 {
+			return &IneligibleError{Rule: "channel"}
+		}
+	}
+
+	if e.Rules.empty() {
+		return nil
+	}
+
+	m, err := member(s, e.GuildID, userID)
+	if err != nil {
+		return err
+	}
+
+	return e.Rules.check(m, time.Now())
+}
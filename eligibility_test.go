@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestEligibilityCheck(t *testing.T) {
+	now := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	// created 2016-04-30, joined a week before now
+	m := &discordgo.Member{
+		User:     &discordgo.User{ID: "175928847299117063"},
+		Roles:    []string{"members", "muted"},
+		JoinedAt: now.Add(-7 * day).Format(time.RFC3339),
+	}
+
+	tests := []struct {
+		rules    *Eligibility
+		expected error
+	}{
+		{nil, nil},
+		{&Eligibility{}, nil},
+		{&Eligibility{RequiredRoles: []string{"members"}}, nil},
+		{&Eligibility{RequiredRoles: []string{"members", "mods"}}, &IneligibleError{Rule: "required_role", RoleID: "mods"}},
+		{&Eligibility{ExcludedRoles: []string{"banned"}}, nil},
+		{&Eligibility{ExcludedRoles: []string{"banned", "muted"}}, &IneligibleError{Rule: "excluded_role", RoleID: "muted"}},
+		{&Eligibility{MinMemberAge: 7 * day}, nil},
+		{&Eligibility{MinMemberAge: 8 * day}, &IneligibleError{Rule: "member_age", Age: 8 * day}},
+		{&Eligibility{MinAccountAge: 365 * day}, nil},
+		{&Eligibility{MinAccountAge: 800 * day}, &IneligibleError{Rule: "account_age", Age: 800 * day}},
+		// roles are checked before ages
+		{
+			&Eligibility{RequiredRoles: []string{"mods"}, MinMemberAge: 8 * day},
+			&IneligibleError{Rule: "required_role", RoleID: "mods"},
+		},
+	}
+
+	for i, test := range tests {
+		err := test.rules.check(m, now)
+
+		if !reflect.DeepEqual(err, test.expected) {
+			t.Errorf("test %d: check returned incorrect error.\nGot: %v\nWant: %v", i, err, test.expected)
+		}
+	}
+}
+
+func TestEligibilityCheckJoinedAt(t *testing.T) {
+	m := &discordgo.Member{User: &discordgo.User{ID: "175928847299117063"}, JoinedAt: "not a time"}
+	rules := &Eligibility{MinMemberAge: day}
+
+	expected := &IneligibleError{Rule: "member_age", Age: day}
+	if err := rules.check(m, time.Now()); !reflect.DeepEqual(err, expected) {
+		t.Errorf("check of a member with an unreadable join time returned %v, want %v", err, expected)
+	}
+}
+
+func TestSnowflakeTime(t *testing.T) {
+	tests := []struct {
+		id       string
+		ok       bool
+		expected time.Time
+	}{
+		{"175928847299117063", true, time.Date(2016, 4, 30, 11, 18, 25, 796*int(time.Millisecond), time.UTC)},
+		{"0", true, time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"not an id", false, time.Time{}},
+	}
+
+	for _, test := range tests {
+		got, err := snowflakeTime(test.id)
+
+		if (err == nil) != test.ok {
+			t.Errorf("snowflakeTime(%q) returned error %v, want ok %v", test.id, err, test.ok)
+		}
+		if !got.Equal(test.expected) {
+			t.Errorf("snowflakeTime(%q) returned %v, want %v", test.id, got, test.expected)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value    string
+		ok       bool
+		expected time.Duration
+	}{
+		{"30d", true, 30 * day},
+		{"2w", true, 14 * day},
+		{"1d", true, day},
+		{"0d", false, 0},
+		{"-3d", false, 0},
+		{"30", false, 0},
+		{"30h", false, 0},
+		{"d", false, 0},
+		{"", false, 0},
+	}
+
+	for _, test := range tests {
+		got, ok := parseAge(test.value)
+
+		if ok != test.ok || ok && got != test.expected {
+			t.Errorf("parseAge(%q) returned (%v, %v)\nWant: (%v, %v)", test.value, got, ok, test.expected, test.ok)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const helpColor = 0x7289da
+
+// permissionNames are the human readable names of the permissions commands
+// may require
+var permissionNames = []struct {
+	perm int
+	name string
+}{
+	{discordgo.PermissionAdministrator, "Administrator"},
+	{discordgo.PermissionManageServer, "Manage Server"},
+	{discordgo.PermissionManageChannels, "Manage Channels"},
+	{discordgo.PermissionManageRoles, "Manage Roles"},
+	{discordgo.PermissionManageMessages, "Manage Messages"},
+	{discordgo.PermissionManageWebhooks, "Manage Webhooks"},
+	{discordgo.PermissionMentionEveryone, "Mention Everyone"},
+}
+
+// describePermissions lists the names of the permissions in perms
+func describePermissions(perms int) string {
+	names := []string{}
+	for _, p := range permissionNames {
+		if perms&p.perm != 0 {
+			names = append(names, p.name)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// commandText returns the translation of key in lang, or fallback if the
+// catalog doesn't have one
+func commandText(lang, key, fallback string) string {
+	if text, ok := catalog.Lookup(lang, key); ok {
+		return text
+	}
+
+	return fallback
+}
+
+// commandSyntax returns how c is invoked with the given prefix
+func commandSyntax(lang, prefix string, c *Command) string {
+	usage := commandText(lang, "usage."+c.Name, c.Usage)
+	return strings.TrimSpace(prefix + c.Name + " " + usage)
+}
+
+// helpEmbed lists every command registered with r. Only their names are
+// shown, the full syntax is too long for an embed field name and is left to
+// the help for each command.
+func helpEmbed(r *Router, lang, prefix string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       catalog.T(lang, "help.title"),
+		Description: catalog.T(lang, "help.more", prefix),
+		Color:       helpColor,
+	}
+
+	for _, c := range r.Commands() {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "`" + prefix + c.Name + "`",
+			Value: commandText(lang, "help."+c.Name, c.Description),
+		})
+	}
+
+	return embed
+}
+
+// commandHelpEmbed describes a single command in detail
+func commandHelpEmbed(c *Command, lang, prefix string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       prefix + c.Name,
+		Description: commandText(lang, "help."+c.Name, c.Description),
+		Color:       helpColor,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: catalog.T(lang, "help.syntax"), Value: "`" + commandSyntax(lang, prefix, c) + "`"},
+		},
+	}
+
+	if len(c.Aliases) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  catalog.T(lang, "help.aliases"),
+			Value: prefix + strings.Join(c.Aliases, ", "+prefix),
+		})
+	}
+
+	if len(c.Examples) > 0 {
+		examples := []string{}
+		for _, e := range c.Examples {
+			examples = append(examples, "`"+prefix+e+"`")
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  catalog.T(lang, "help.examples"),
+			Value: strings.Join(examples, "\n"),
+		})
+	}
+
+	if c.Permissions != 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  catalog.T(lang, "help.permissions"),
+			Value: describePermissions(c.Permissions),
+		})
+	}
+
+	return embed
+}
+
+func handleHelp(ctx *Context) error {
+	embed := helpEmbed(ctx.Router, ctx.Lang(), ctx.Prefix)
+
+	if len(ctx.Args) > 0 {
+		name := strings.TrimPrefix(ctx.Args[0], ctx.Prefix)
+		c, ok := ctx.Router.Lookup(name)
+		if !ok {
+			return ctx.Reply(ctx.T("help.unknown_command", name))
+		}
+		embed = commandHelpEmbed(c, ctx.Lang(), ctx.Prefix)
+	}
+
+	_, err := ctx.Session.ChannelMessageSendEmbed(ctx.Message.ChannelID, embed)
+	return err
+}
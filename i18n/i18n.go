@@ -0,0 +1,191 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PluralRule returns the CLDR plural category ("zero", "one", "two", "few",
+// "many" or "other") a count falls into
+type PluralRule func(n int) string
+
+// oneOther is the plural rule for languages that only distinguish one from
+// everything else, like English, German and Spanish
+func oneOther(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// pluralRules holds the plural rule for each language that doesn't use oneOther
+var pluralRules = map[string]PluralRule{}
+
+// message is a single catalog entry, either plain text or a set of plural forms
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+func (m *message) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &m.text); err == nil {
+		return nil
+	}
+
+	return json.Unmarshal(b, &m.plural)
+}
+
+// Catalog holds the translations of every message in every loaded language
+type Catalog struct {
+	// Fallback is the language used for messages missing from another language
+	Fallback string
+
+	mu       sync.RWMutex
+	messages map[string]map[string]message
+}
+
+// NewCatalog creates an empty Catalog that falls back to fallback
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		Fallback: fallback,
+		messages: make(map[string]map[string]message),
+	}
+}
+
+// Add parses a JSON object of message keys to translations for lang. A
+// translation is either a string or an object of plural category to string.
+func (c *Catalog) Add(lang string, data []byte) error {
+	messages := make(map[string]message)
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("parsing %s messages: %v", lang, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.messages[lang] == nil {
+		c.messages[lang] = messages
+		return nil
+	}
+	for key, m := range messages {
+		c.messages[lang][key] = m
+	}
+
+	return nil
+}
+
+// LoadDir adds every <lang>.json file in dir to the catalog
+func (c *Catalog) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+
+		lang := strings.TrimSuffix(filepath.Base(f), ".json")
+		if err := c.Add(lang, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Languages returns the loaded languages in sorted order
+func (c *Catalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	langs := []string{}
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	return langs
+}
+
+// Has reports whether lang has been loaded
+func (c *Catalog) Has(lang string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.messages[lang]
+	return ok
+}
+
+// lookup finds key in lang, then in the fallback language
+func (c *Catalog) lookup(lang, key string) (message, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if m, ok := c.messages[lang][key]; ok {
+		return m, true
+	}
+	m, ok := c.messages[c.Fallback][key]
+	return m, ok
+}
+
+// Lookup returns the untranslated format string for key in lang and whether
+// one exists
+func (c *Catalog) Lookup(lang, key string) (string, bool) {
+	m, ok := c.lookup(lang, key)
+	if !ok || m.plural != nil {
+		return "", false
+	}
+
+	return m.text, true
+}
+
+// T translates key into lang, formatting args into it like fmt.Sprintf. A key
+// missing from every language is returned as is.
+func (c *Catalog) T(lang, key string, args ...interface{}) string {
+	m, ok := c.lookup(lang, key)
+	if !ok {
+		return key
+	}
+
+	format := m.text
+	if m.plural != nil {
+		format = m.plural["other"]
+	}
+	if len(args) == 0 {
+		return format
+	}
+
+	return fmt.Sprintf(format, args...)
+}
+
+// Plural translates key into lang choosing the plural form for n. n is the
+// first argument formatted into the message, followed by args.
+func (c *Catalog) Plural(lang, key string, n int, args ...interface{}) string {
+	m, ok := c.lookup(lang, key)
+	if !ok {
+		return key
+	}
+
+	format := m.text
+	if m.plural != nil {
+		rule, ok := pluralRules[lang]
+		if !ok {
+			rule = oneOther
+		}
+
+		var found bool
+		if format, found = m.plural[rule(n)]; !found {
+			format = m.plural["other"]
+		}
+	}
+
+	return fmt.Sprintf(format, append([]interface{}{n}, args...)...)
+}
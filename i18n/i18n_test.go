@@ -0,0 +1,106 @@
+package i18n
+
+import (
+	"testing"
+)
+
+func testCatalog(t *testing.T) *Catalog {
+	c := NewCatalog("en")
+
+	files := map[string]string{
+		"en": `{
+			"hello": "Hello %s",
+			"bye": "Bye",
+			"votes": {"one": "%d vote", "other": "%d votes"},
+			"votes_for": {"one": "%d vote for %s", "other": "%d votes for %s"}
+		}`,
+		"de": `{
+			"hello": "Hallo %s",
+			"votes": {"one": "%d Stimme", "other": "%d Stimmen"}
+		}`,
+	}
+
+	for lang, data := range files {
+		if err := c.Add(lang, []byte(data)); err != nil {
+			t.Fatalf("Add returned unexpected error: %v", err)
+		}
+	}
+
+	return c
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		data string
+		ok   bool
+	}{
+		{`{"a": "b"}`, true},
+		{`{"a": {"one": "b", "other": "c"}}`, true},
+		{`{"a": 1}`, false},
+		{`["a"]`, false},
+	}
+
+	for _, test := range tests {
+		err := NewCatalog("en").Add("en", []byte(test.data))
+
+		if err != nil && test.ok {
+			t.Errorf("Add returned unexpected error for %s: %v", test.data, err)
+		} else if err == nil && !test.ok {
+			t.Errorf("Add didn't return an error for %s", test.data)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	c := testCatalog(t)
+
+	tests := []struct {
+		lang     string
+		key      string
+		args     []interface{}
+		expected string
+	}{
+		{"en", "hello", []interface{}{"world"}, "Hello world"},
+		{"de", "hello", []interface{}{"Welt"}, "Hallo Welt"},
+		{"de", "bye", nil, "Bye"},
+		{"fr", "hello", []interface{}{"monde"}, "Hello monde"},
+		{"en", "missing", nil, "missing"},
+	}
+
+	for _, test := range tests {
+		got := c.T(test.lang, test.key, test.args...)
+
+		if got != test.expected {
+			t.Errorf("T(%q, %q) returned incorrect message\nGot: %s\nWant: %s",
+				test.lang, test.key, got, test.expected)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	c := testCatalog(t)
+
+	tests := []struct {
+		lang     string
+		key      string
+		n        int
+		args     []interface{}
+		expected string
+	}{
+		{"en", "votes", 0, nil, "0 votes"},
+		{"en", "votes", 1, nil, "1 vote"},
+		{"en", "votes", 2, nil, "2 votes"},
+		{"de", "votes", 1, nil, "1 Stimme"},
+		{"de", "votes", 5, nil, "5 Stimmen"},
+		{"de", "votes_for", 1, []interface{}{"Pizza"}, "1 vote for Pizza"},
+	}
+
+	for _, test := range tests {
+		got := c.Plural(test.lang, test.key, test.n, test.args...)
+
+		if got != test.expected {
+			t.Errorf("Plural(%q, %q, %d) returned incorrect message\nGot: %s\nWant: %s",
+				test.lang, test.key, test.n, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"sync"
+
+	"github.com/mroseman95/discord-poll-bot/choose"
+	"github.com/mroseman95/discord-poll-bot/i18n"
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+const defaultLanguage = "en"
+
+// catalog holds every reply the bot can send, in every language it speaks
+var catalog = i18n.NewCatalog(defaultLanguage)
+
+// localesDir returns the directory the message catalog is loaded from
+func localesDir() string {
+	if dir := os.Getenv("DISCORD_BOT_LOCALES_DIR"); dir != "" {
+		return dir
+	}
+
+	return "locales"
+}
+
+// UserConfig holds the settings a user has chosen for themselves
+type UserConfig struct {
+	Language string `json:"language,omitempty"`
+	// NoReminders opts the user out of reminders to vote
+	NoReminders bool `json:"no_reminders,omitempty"`
+}
+
+// userStore caches user settings in memory and persists them to disk
+type userStore struct {
+	mu    sync.RWMutex
+	file  string
+	users map[string]UserConfig
+}
+
+var userConfigs = &userStore{file: "users.json", users: make(map[string]UserConfig)}
+
+// load reads the persisted user settings
+func (u *userStore) load() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return loadJSON(u.file, &u.users)
+}
+
+// Get returns the settings for userID
+func (u *userStore) Get(userID string) UserConfig {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.users[userID]
+}
+
+// Update applies f to the settings for userID and persists the result
+func (u *userStore) Update(userID string, f func(*UserConfig)) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	cfg := u.users[userID]
+	f(&cfg)
+	u.users[userID] = cfg
+
+	return saveJSON(u.file, u.users)
+}
+
+// language returns the language to talk to userID in. A user's own choice
+// wins over their guild's.
+func language(guildID, userID string) string {
+	if lang := userConfigs.Get(userID).Language; lang != "" {
+		return lang
+	}
+	if lang := guildConfigs.Get(guildID).Language; lang != "" {
+		return lang
+	}
+
+	return defaultLanguage
+}
+
+// Lang returns the language to reply to the invoking user in
+func (ctx *Context) Lang() string {
+	return language(ctx.GuildID(), ctx.Message.Author.ID)
+}
+
+// T translates key for the invoking user
+func (ctx *Context) T(key string, args ...interface{}) string {
+	return catalog.T(ctx.Lang(), key, args...)
+}
+
+// Tn translates key for the invoking user, choosing the plural form for n
+func (ctx *Context) Tn(key string, n int, args ...interface{}) string {
+	return catalog.Plural(ctx.Lang(), key, n, args...)
+}
+
+// errorKeys maps errors users can cause to the message explaining them
+var errorKeys = map[error]string{
+	poll.ErrTooFewOptions: "poll.too_few_options",
+	poll.ErrAlreadyVoted:  "poll.already_voted",
+	poll.ErrUnknownOption: "poll.unknown_option",
+
+	choose.ErrEmptyOption:  "choose.empty_option",
+	choose.ErrMixedWeights: "choose.mixed_weights",
+	choose.ErrPercentTotal: "choose.percent_total",
+}
+
+func handleLanguage(ctx *Context) error {
+	lang := ""
+	if len(ctx.Args) > 0 {
+		lang = ctx.Args[0]
+		if !catalog.Has(lang) {
+			return ctx.Reply(ctx.T("config.invalid_language", lang, languageList()))
+		}
+	}
+
+	err := userConfigs.Update(ctx.Message.Author.ID, func(cfg *UserConfig) {
+		cfg.Language = lang
+	})
+	if err != nil {
+		return err
+	}
+
+	if lang == "" {
+		return ctx.Reply(ctx.T("language.reset"))
+	}
+	return ctx.Reply(ctx.T("language.set"))
+}
+
+// languageList lists the codes of the languages the bot speaks
+func languageList() string {
+	list := ""
+	for i, lang := range catalog.Languages() {
+		if i > 0 {
+			list += ", "
+		}
+		list += lang + " (" + catalog.T(lang, "language.name") + ")"
+	}
+
+	return list
+}
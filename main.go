@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -13,6 +11,8 @@ import (
 
 var botID string
 
+var router = NewRouter(commandPrefixes)
+
 func main() {
 	rand.Seed(time.Now().Unix())
 	token := os.Getenv("DISCORD_BOT_AUTH_TOKEN")
@@ -29,44 +29,215 @@ func main() {
 
 	botID = u.ID
 
-	dg.AddHandler(handleMessage)
-
-	err = dg.Open()
+	err = guildConfigs.load()
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Printf("Bot %v is running\n", botID)
+	err = userConfigs.load()
+	if err != nil {
+		panic(err)
+	}
 
-	<-make(chan struct{})
-	return
-}
+	err = catalog.LoadDir(localesDir())
+	if err != nil {
+		panic(err)
+	}
 
-func handleMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
-	if m.Author.ID == botID {
-		return
+	pollSecret, err = loadSecret("DISCORD_BOT_POLL_SECRET", "poll.secret")
+	if err != nil {
+		panic(err)
 	}
 
-	ok, err := regexp.MatchString("^!choose ", m.Content)
+	err = polls.load()
 	if err != nil {
 		panic(err)
 	}
-	if ok {
-		handleChoose(s, m)
+
+	err = schedules.load()
+	if err != nil {
+		panic(err)
 	}
-}
 
-func handleChoose(s *discordgo.Session, m *discordgo.MessageCreate) {
-	options := strings.Split(m.Content[8:], ", ")
+	err = templates.load()
+	if err != nil {
+		panic(err)
+	}
 
-	option := options[rand.Intn(len(options))]
+	err = webhooks.load()
+	if err != nil {
+		panic(err)
+	}
 
-	fmt.Printf("%q chose: %s\n", options, option)
+	router.Use(logCommands, recoverCommands, newCooldowns().middleware)
+	err = registerCommands(router)
+	if err != nil {
+		panic(err)
+	}
 
-	msg := "How about " + option
+	dg.AddHandler(handleMessage)
+	dg.AddHandler(handleReactionAdd)
+	dg.AddHandler(handleReactionRemove)
+	dg.AddHandler(handleVoiceStateUpdate)
+	dg.AddHandler(handleReady)
+	dg.AddHandler(handleResumed)
+	dg.AddHandler(handleRateLimit)
 
-	_, err := s.ChannelMessageSend(m.ChannelID, msg)
+	err = dg.Open()
 	if err != nil {
 		panic(err)
 	}
+
+	scheduleDeadlines(dg)
+	go runReminders(dg)
+	go runSchedules(dg)
+
+	fmt.Printf("Bot %v is running\n", botID)
+
+	<-make(chan struct{})
+	return
+}
+
+// registerCommands adds every bot command to r
+func registerCommands(r *Router) error {
+	commands := []*Command{
+		{
+			Name:        "help",
+			Usage:       "[command]",
+			Description: "Lists the commands, or explains one command",
+			Examples:    []string{"help", "help choose"},
+			Args:        ArgSpec{Max: 1},
+			Handler:     handleHelp,
+		},
+		{
+			Name:        "choose",
+			Aliases:     []string{"pick"},
+			Usage:       "[<count> of] <option>[:weight|:percent%], <option>, ...",
+			Description: "Picks one of the given options at random, or as many as asked for without repeats, weighted options are likelier",
+			Examples:    []string{"choose pizza, tacos, sushi", "choose pizza:3, tacos:1, sushi:2", "choose pizza:50%, tacos, sushi", "choose 2 of Ana, Ben, Cleo, Dan"},
+			Args:        ArgSpec{Separator: ",", Min: 1},
+			Handler:     handleChoose,
+		},
+		{
+			Name:        "shuffle",
+			Usage:       "<option>, <option>, ...",
+			Description: "Puts the given options in a random order, weighted options tend to come first",
+			Examples:    []string{"shuffle Ana, Ben, Cleo, Dan"},
+			Args:        ArgSpec{Separator: ",", Min: 1},
+			Handler:     handleShuffle,
+		},
+		{
+			Name:        "order",
+			Aliases:     []string{"turns"},
+			Usage:       "[<player>, <player>, ...]",
+			Description: "Picks a turn order for the given players, or for everyone in your voice channel",
+			Examples:    []string{"order Ana, Ben, Cleo", "order"},
+			Args:        ArgSpec{Separator: ","},
+			Handler:     handleOrder,
+		},
+		{
+			Name:        "poll",
+			Usage:       "\"<question>\" <option>, <option>, ... [--multi] [--roles] [--secret] [--anonymous] [--role=@role] [--require-role=@role,...] [--exclude-role=@role,...] [--min-member-age=<age>] [--min-account-age=<age>] [--voters=@user,...] [--voice] [--voice-policy=fixed|add|remove|follow] [--autoclose] [--closes=<duration|time>] [--remind=<interval>] [--chart=bar|pie] [--webhooks=<name>,...] | close <id> | results <id> | chart <id> [bar|pie] | mirror <id> #channel ... | edit <id> [question \"<question>\" | rename <option>, <name> | add <option> | remove <option> --void|--move-to=<option>] | schedule \"<cron>\" <poll> [--close-previous] | schedule list|pause|resume|delete [id]",
+			Description: "Starts a poll voted on with reactions, options may start with their own emoji",
+			Examples: []string{
+				"poll \"What should we eat?\" pizza, tacos, sushi",
+				"poll \"Which days work?\" Friday, Saturday, Sunday --multi",
+				"poll \"Who should lead the guild?\" Alice, Bob --secret --role=@Members",
+				"poll \"Movie night?\" Friday, Saturday --closes=48h",
+				"poll \"Next event?\" Quiz, Karaoke --closes=24h --webhooks=announcements",
+				"poll \"Ship it?\" Yes, No --voters=@Ana,@Ben --autoclose",
+				"poll \"Pick your team\" @Red:5, @Blue:5 --roles",
+				"poll \"Next raid leader?\" Ana, Ben --require-role=@Raiders --min-member-age=30d",
+				"poll schedule \"0 18 * * fri\" \"What game tonight?\" Chess, Go --close-previous",
+				"poll schedule pause 1",
+				"poll close 3",
+				"poll chart 3 pie",
+				"poll mirror 3 #announcements #general",
+				"poll edit 3 rename Piza, Pizza",
+				"poll edit 3 remove Tacos --move-to=Burritos",
+				"poll edit 3",
+			},
+			Handler: handlePoll,
+		},
+		{
+			Name:        "vcpoll",
+			Usage:       "\"<question>\" <option>, <option>, ... [--autoclose] [--voice-policy=fixed|add|remove|follow] [poll options]",
+			Description: "Starts a poll only the people in your voice channel can vote on",
+			Examples: []string{
+				"vcpoll \"Which map next?\" Dust, Inferno, Mirage --autoclose",
+				"vcpoll \"Keep playing?\" Yes, No --voice-policy=follow",
+			},
+			Handler: handleVCPoll,
+		},
+		{
+			Name:        "template",
+			Usage:       "save <name> <poll> | use <name> [overrides] | list | delete <name>",
+			Description: "Saves polls you run often as templates and creates polls from them",
+			Examples: []string{
+				"template save movies \"Which genre tonight?\" Comedy, Horror, Sci-fi --closes=24h",
+				"template use movies",
+				"template use movies \"Which genre on Saturday?\" --multi",
+				"template list",
+			},
+			Args:    ArgSpec{Min: 1},
+			Handler: handleTemplate,
+		},
+		{
+			Name:        "webhook",
+			Usage:       "create <name> [#channel] [--username=<name>] [--avatar=<url>] [--format=text|embed] | add <name> <url> [options] | set <name> <options> | list | delete <name>",
+			Description: "Manages the webhooks poll results can be published to with --webhooks",
+			Examples: []string{
+				"webhook create announcements #announcements --username=\"Poll Results\" --format=embed",
+				"webhook add partners https://discord.com/api/webhooks/123/abc --format=text",
+				"webhook set partners --avatar=https://example.com/logo.png",
+				"webhook list",
+			},
+			Args:        ArgSpec{Min: 1},
+			Permissions: discordgo.PermissionManageWebhooks,
+			Handler:     handleWebhook,
+		},
+		{
+			Name:        "config",
+			Usage:       "<setting> [value]",
+			Description: "Changes a setting for this server, leave the value empty to reset it",
+			Examples:    []string{"config prefix ?", "config language es", "config pins on", "config reminders channel", "config timezone Europe/Berlin"},
+			Args:        ArgSpec{Min: 1},
+			Permissions: discordgo.PermissionManageServer,
+			Handler:     handleConfig,
+		},
+		{
+			Name:        "language",
+			Usage:       "[language]",
+			Description: "Sets the language the bot replies to you in, leave it empty to use the server's",
+			Examples:    []string{"language de", "language"},
+			Args:        ArgSpec{Max: 1},
+			Handler:     handleLanguage,
+		},
+		{
+			Name:        "reminders",
+			Usage:       "[on|off]",
+			Description: "Turns reminders to vote on polls on or off for you",
+			Examples:    []string{"reminders off", "reminders"},
+			Args:        ArgSpec{Max: 1},
+			Handler:     handleReminders,
+		},
+	}
+
+	for _, c := range commands {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func handleMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author.ID == botID {
+		return
+	}
+
+	if !router.Dispatch(s, m) && isDirectMessage(s, m.ChannelID) {
+		handleBallot(s, m)
+	}
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// memberPageSize is the most members discord returns for one GuildMembers call
+const memberPageSize = 1000
+
+var (
+	roleMentionPattern = regexp.MustCompile(`^<@&(\d+)>$|^(\d+)$`)
+	userMentionPattern = regexp.MustCompile(`<@!?(\d+)>`)
+)
+
+// parseRole returns the role ID in a role mention or bare ID
+func parseRole(value string) (string, bool) {
+	m := roleMentionPattern.FindStringSubmatch(value)
+	if m == nil {
+		return "", false
+	}
+
+	return m[1] + m[2], true
+}
+
+// parseMentions returns the IDs of the users mentioned in value, once each
+func parseMentions(value string) []string {
+	users := []string{}
+	for _, m := range userMentionPattern.FindAllStringSubmatch(value, -1) {
+		if !contains(users, m[1]) {
+			users = append(users, m[1])
+		}
+	}
+
+	return users
+}
+
+// guildMembers returns every member of guildID, adding them to the state so
+// their channel permissions can be worked out
+func guildMembers(s *discordgo.Session, guildID string) ([]*discordgo.Member, error) {
+	members := []*discordgo.Member{}
+	after := ""
+	for {
+		page, err := s.GuildMembers(guildID, after, memberPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range page {
+			m.GuildID = guildID
+			s.State.MemberAdd(m)
+		}
+		members = append(members, page...)
+
+		if len(page) < memberPageSize {
+			return members, nil
+		}
+		after = page[len(page)-1].User.ID
+	}
+}
+
+// hasRole reports whether m has the role roleID
+func hasRole(m *discordgo.Member, roleID string) bool {
+	return contains(m.Roles, roleID)
+}
+
+// eligibleVoters returns the IDs of the people who can read channelID, meet
+// rules and, when roleID is set, have that role. Bots never vote.
+func eligibleVoters(s *discordgo.Session, guildID, channelID, roleID string, rules *Eligibility) ([]string, error) {
+	members, err := guildMembers(s, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	voters := []string{}
+	for _, m := range members {
+		if m.User.Bot || (roleID != "" && !hasRole(m, roleID)) || rules.check(m, now) != nil {
+			continue
+		}
+
+		perms, err := s.State.UserChannelPermissions(m.User.ID, channelID)
+		if err != nil || perms&(discordgo.PermissionReadMessages|discordgo.PermissionAdministrator) == 0 {
+			continue
+		}
+
+		voters = append(voters, m.User.ID)
+	}
+
+	return voters, nil
+}
+
+// isDirectMessage reports whether channelID is a direct message channel
+func isDirectMessage(s *discordgo.Session, channelID string) bool {
+	c, err := channel(s, channelID)
+	if err != nil {
+		return false
+	}
+
+	return c.Type == discordgo.ChannelTypeDM
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var channelMentionPattern = regexp.MustCompile(`^<#(\d+)>$|^(\d+)$`)
+
+// parseChannel returns the channel ID in a channel mention or bare ID
+func parseChannel(value string) (string, bool) {
+	m := channelMentionPattern.FindStringSubmatch(value)
+	if m == nil {
+		return "", false
+	}
+
+	return m[1] + m[2], true
+}
+
+// canMirror returns a userError unless poll e can be shown in a guild other
+// than its own: votes there have to mean the same as votes at home
+func canMirror(e *pollEntry, guildID string) error {
+	// role polls hand out roles of the poll's guild, and secret ballots go
+	// to members of the poll's channel
+	if e.Roles != nil || e.Secret {
+		return newUserError("mirror.unsupported", e.ID)
+	}
+	if guildID != e.GuildID && (e.RoleID != "" || e.Eligible != nil || !e.Rules.empty()) {
+		return newUserError("mirror.restricted", e.ID)
+	}
+
+	return nil
+}
+
+// handlePollMirror posts poll id again in each of the channels given, all of
+// the messages counting towards the same votes
+func handlePollMirror(ctx *Context) error {
+	id, err := pollArg(ctx)
+	if err != nil {
+		return err
+	}
+	if len(ctx.Args) < 2 {
+		return &UsageError{ctx.Command}
+	}
+
+	var e pollEntry
+	found := polls.View(id, func(entry *pollEntry) { e = entry.snapshot() })
+	if !found || !e.inGuild(ctx.GuildID()) {
+		return newUserError("poll.not_found", id)
+	}
+	if !canManagePoll(ctx, &e) {
+		return &PermissionError{ctx.Command}
+	}
+	if e.Closed {
+		return newUserError("poll.already_closed", id)
+	}
+
+	targets := []*discordgo.Channel{}
+	for _, arg := range ctx.Args[1:] {
+		channelID, ok := parseChannel(arg)
+		if !ok {
+			return newUserError("mirror.invalid_channel", arg)
+		}
+		c, err := channel(ctx.Session, channelID)
+		if err != nil || c.GuildID == "" {
+			return newUserError("mirror.invalid_channel", arg)
+		}
+		for _, msg := range e.messages() {
+			if msg.ChannelID == c.ID {
+				return newUserError("mirror.already", id, c.ID)
+			}
+		}
+		if err := canMirror(&e, c.GuildID); err != nil {
+			return err
+		}
+
+		// only post where the one asking could post themselves
+		ok, err = hasPermissions(ctx.Session, ctx.Message.Author.ID, c.ID, discordgo.PermissionSendMessages)
+		if err != nil || !ok {
+			return newUserError("mirror.no_permission", c.ID)
+		}
+		targets = append(targets, c)
+	}
+
+	for _, c := range targets {
+		if err := mirrorPoll(ctx.Session, id, c); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Reply(ctx.Tn("mirror.created", len(targets), id))
+}
+
+// mirrorPoll posts poll id in c and adds the message to its mirrors
+func mirrorPoll(s *discordgo.Session, id int, c *discordgo.Channel) error {
+	var e pollEntry
+	if !polls.View(id, func(entry *pollEntry) { e = entry.snapshot() }) {
+		return newUserError("poll.not_found", id)
+	}
+
+	msg, err := s.ChannelMessageSendEmbed(c.ID, pollEmbed(&e, language(c.GuildID, "")))
+	if err != nil {
+		return err
+	}
+
+	err = polls.Update(id, func(e *pollEntry) error {
+		e.Mirrors = append(e.Mirrors, pollMirror{c.GuildID, c.ID, msg.ID})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, emoji := range e.Emoji {
+		if err := s.MessageReactionAdd(c.ID, msg.ID, emoji); err != nil {
+			fmt.Printf("failed to add %s to mirror of poll %d: %v\n", emoji, id, err)
+		}
+	}
+
+	fmt.Printf("mirrored poll %d in %s\n", id, c.ID)
+	return nil
+}
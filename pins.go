@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxPins is the most messages discord allows to be pinned in one channel
+const maxPins = 50
+
+// pinPoll pins poll id's message if its guild asked for polls to be pinned
+// and the channel has room for another pin
+func pinPoll(s *discordgo.Session, id int) error {
+	var guildID, channelID, messageID string
+	polls.View(id, func(e *pollEntry) {
+		guildID, channelID, messageID = e.GuildID, e.ChannelID, e.MessageID
+	})
+	if !guildConfigs.Get(guildID).PinPolls {
+		return nil
+	}
+
+	pinned, err := s.ChannelMessagesPinned(channelID)
+	if err != nil {
+		return err
+	}
+	if len(pinned) >= maxPins {
+		fmt.Printf("not pinning poll %d, %s already has %d pins\n", id, channelID, len(pinned))
+		return nil
+	}
+
+	if err := s.ChannelMessagePin(channelID, messageID); err != nil {
+		return err
+	}
+
+	return polls.Update(id, func(e *pollEntry) error {
+		e.Pinned = true
+		return nil
+	})
+}
+
+// unpinPoll unpins poll id's message, but only if the bot pinned it
+func unpinPoll(s *discordgo.Session, id int) error {
+	var pinned bool
+	var channelID, messageID string
+	polls.View(id, func(e *pollEntry) {
+		pinned, channelID, messageID = e.Pinned, e.ChannelID, e.MessageID
+	})
+	if !pinned {
+		return nil
+	}
+
+	if err := s.ChannelMessageUnpin(channelID, messageID); err != nil {
+		return err
+	}
+
+	return polls.Update(id, func(e *pollEntry) error {
+		e.Pinned = false
+		return nil
+	})
+}
@@ -1,14 +1,41 @@
 package poll
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 )
 
+// Errors returned when creating or voting on a Poll
+var (
+	ErrTooFewOptions   = errors.New("must supply at least two options")
+	ErrAlreadyVoted    = errors.New("this voter already voted on this poll")
+	ErrUnknownOption   = errors.New("unknown option for this poll")
+	ErrNotVoted        = errors.New("this voter hasn't voted for this option")
+	ErrAnonymous       = errors.New("votes on an anonymous poll can't be taken back")
+	ErrNoChoice        = errors.New("a ballot must choose at least one option")
+	ErrDuplicateOption = errors.New("this poll already has that option")
+)
+
 // Poll contains information relevent to a specific poll
 type Poll struct {
 	Options []string
 	Votes   map[string][]Vote
+	// MultiChoice allows a voter to vote for more than one option
+	MultiChoice bool
+
+	// Anonymous polls never store who voted for what. Counts holds the number
+	// of votes for each option and, apart from it, Voters holds a hash of each
+	// voter keyed with Key, so nobody can vote twice. Key is left out of the
+	// poll's JSON: stored next to the hashes it would let them be matched to
+	// voters, so whoever stores the poll keeps it elsewhere.
+	Anonymous bool            `json:",omitempty"`
+	Counts    map[string]int  `json:",omitempty"`
+	Voters    map[string]bool `json:",omitempty"`
+	Key       []byte          `json:"-"`
 }
 
 // Vote represents a vote by one person towards one option
@@ -20,10 +47,37 @@ type Vote struct {
 // NewPoll creates a new Poll type with the given options and returns a pointer to it.
 func NewPoll(options []string) (*Poll, error) {
 	if len(options) < 2 {
-		return nil, errors.New("must supply at least two options")
+		return nil, ErrTooFewOptions
 	}
 
-	return &Poll{options, make(map[string][]Vote)}, nil
+	return &Poll{Options: options, Votes: make(map[string][]Vote)}, nil
+}
+
+// NewAnonymousPoll creates a Poll that counts votes without recording who
+// cast them
+func NewAnonymousPoll(options []string) (*Poll, error) {
+	p, err := NewPoll(options)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	p.Anonymous = true
+	p.Counts = make(map[string]int)
+	p.Voters = make(map[string]bool)
+	p.Key = key
+	return p, nil
+}
+
+// hash returns the keyed hash an anonymous poll stores in place of voter
+func (p Poll) hash(voter string) string {
+	mac := hmac.New(sha256.New, p.Key)
+	mac.Write([]byte(voter))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
 func (p Poll) equal(q Poll) bool {
@@ -36,13 +90,22 @@ func (p Poll) equal(q Poll) bool {
 	return false
 }
 
-// Vote casts a vote towards one of the options in the given Poll
+// Vote casts a vote towards one of the options in the given Poll. In a
+// MultiChoice poll a voter may vote once for each option, unless the poll is
+// Anonymous where each voter casts a single ballot.
 func (p *Poll) Vote(option, voter string) error {
+	if p.Anonymous {
+		return p.Cast(voter, option)
+	}
+
 	// check if voter has already voted
-	for _, votes := range p.Votes {
+	for o, votes := range p.Votes {
+		if p.MultiChoice && o != option {
+			continue
+		}
 		for _, v := range votes {
 			if v.Voter == voter {
-				return errors.New("this voter already voted on this poll")
+				return ErrAlreadyVoted
 			}
 		}
 	}
@@ -50,12 +113,254 @@ func (p *Poll) Vote(option, voter string) error {
 	// check if the given option exists
 	for _, o := range p.Options {
 		if o == option {
+			if p.Votes == nil {
+				p.Votes = make(map[string][]Vote)
+			}
 			p.Votes[o] = append(p.Votes[o], Vote{o, voter})
 			return nil
 		}
 	}
 
-	return errors.New("unkown option for this poll")
+	return ErrUnknownOption
+}
+
+// Cast records voter's whole ballot on an anonymous poll, one vote for each
+// of options, keeping nothing that links voter to the options chosen
+func (p *Poll) Cast(voter string, options ...string) error {
+	if !p.Anonymous {
+		for _, o := range options {
+			if err := p.Vote(o, voter); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(options) == 0 {
+		return ErrNoChoice
+	}
+	if !p.MultiChoice && len(options) > 1 {
+		return ErrAlreadyVoted
+	}
+	for i, o := range options {
+		if !p.hasOption(o) {
+			return ErrUnknownOption
+		}
+		for _, prev := range options[:i] {
+			if prev == o {
+				return ErrAlreadyVoted
+			}
+		}
+	}
+
+	h := p.hash(voter)
+	if p.Voters[h] {
+		return ErrAlreadyVoted
+	}
+
+	if p.Counts == nil {
+		p.Counts = make(map[string]int)
+	}
+	if p.Voters == nil {
+		p.Voters = make(map[string]bool)
+	}
+	for _, o := range options {
+		p.Counts[o]++
+	}
+	p.Voters[h] = true
+	return nil
+}
+
+// hasOption reports whether option is one of the poll's options
+func (p Poll) hasOption(option string) bool {
+	for _, o := range p.Options {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasVoted reports whether voter has voted on the poll
+func (p Poll) HasVoted(voter string) bool {
+	if p.Anonymous {
+		return p.Voters[p.hash(voter)]
+	}
+
+	return len(p.Choices(voter)) > 0
+}
+
+// Count returns the number of votes for option
+func (p Poll) Count(option string) int {
+	if p.Anonymous {
+		return p.Counts[option]
+	}
+
+	return len(p.Votes[option])
+}
+
+// Unvote takes back a vote the voter cast towards option. Votes on an
+// Anonymous poll can't be taken back, nothing records which option they went to.
+func (p *Poll) Unvote(option, voter string) error {
+	if p.Anonymous {
+		return ErrAnonymous
+	}
+
+	votes := p.Votes[option]
+	for i, v := range votes {
+		if v.Voter == voter {
+			votes = append(votes[:i], votes[i+1:]...)
+			if len(votes) == 0 {
+				delete(p.Votes, option)
+			} else {
+				p.Votes[option] = votes
+			}
+			return nil
+		}
+	}
+
+	return ErrNotVoted
+}
+
+// AddOption adds option to the end of the poll's options
+func (p *Poll) AddOption(option string) error {
+	if p.hasOption(option) {
+		return ErrDuplicateOption
+	}
+
+	p.Options = append(p.Options, option)
+	return nil
+}
+
+// RenameOption renames option to name, keeping its votes
+func (p *Poll) RenameOption(option, name string) error {
+	if !p.hasOption(option) {
+		return ErrUnknownOption
+	}
+	if p.hasOption(name) {
+		return ErrDuplicateOption
+	}
+
+	for i, o := range p.Options {
+		if o == option {
+			p.Options[i] = name
+		}
+	}
+
+	if votes, ok := p.Votes[option]; ok {
+		for i := range votes {
+			votes[i].Option = name
+		}
+		p.Votes[name] = votes
+		delete(p.Votes, option)
+	}
+	if n, ok := p.Counts[option]; ok {
+		p.Counts[name] = n
+		delete(p.Counts, option)
+	}
+
+	return nil
+}
+
+// RemoveOption removes option from the poll. Its votes move to moveTo unless
+// that is empty, when they are voided. It returns the voters whose vote for
+// option was voided or moved, always none for an Anonymous poll.
+//
+// Votes on an Anonymous MultiChoice poll can't be moved: a ballot that chose
+// both options would count twice for moveTo.
+func (p *Poll) RemoveOption(option, moveTo string) ([]string, error) {
+	if !p.hasOption(option) || moveTo != "" && !p.hasOption(moveTo) {
+		return nil, ErrUnknownOption
+	}
+	if option == moveTo {
+		return nil, ErrDuplicateOption
+	}
+	if len(p.Options) <= 2 {
+		return nil, ErrTooFewOptions
+	}
+	if p.Anonymous && p.MultiChoice && moveTo != "" && p.Counts[option] > 0 {
+		return nil, ErrAnonymous
+	}
+
+	for i, o := range p.Options {
+		if o == option {
+			p.Options = append(p.Options[:i:i], p.Options[i+1:]...)
+			break
+		}
+	}
+
+	if p.Anonymous {
+		if moveTo != "" && p.Counts[option] > 0 {
+			p.Counts[moveTo] += p.Counts[option]
+		}
+		delete(p.Counts, option)
+		return nil, nil
+	}
+
+	voters := []string{}
+	for _, v := range p.Votes[option] {
+		voters = append(voters, v.Voter)
+		// a voter who also chose moveTo keeps the one vote for it
+		if moveTo != "" && !contains(p.Choices(v.Voter), moveTo) {
+			p.Votes[moveTo] = append(p.Votes[moveTo], Vote{moveTo, v.Voter})
+		}
+	}
+	delete(p.Votes, option)
+
+	return voters, nil
+}
+
+// contains reports whether s holds v
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Copy returns a deep copy of the poll, safe to read while p changes
+func (p Poll) Copy() *Poll {
+	votes := make(map[string][]Vote, len(p.Votes))
+	for o, v := range p.Votes {
+		votes[o] = append([]Vote(nil), v...)
+	}
+	p.Options = append([]string(nil), p.Options...)
+	p.Votes = votes
+
+	if p.Anonymous {
+		counts := make(map[string]int, len(p.Counts))
+		for o, n := range p.Counts {
+			counts[o] = n
+		}
+		voters := make(map[string]bool, len(p.Voters))
+		for h := range p.Voters {
+			voters[h] = true
+		}
+		p.Counts, p.Voters = counts, voters
+		p.Key = append([]byte(nil), p.Key...)
+	}
+
+	return &p
+}
+
+// Choices returns the options voter has voted for, in option order. It's
+// always empty for an Anonymous poll.
+func (p Poll) Choices(voter string) []string {
+	choices := []string{}
+	for _, o := range p.Options {
+		for _, v := range p.Votes[o] {
+			if v.Voter == voter {
+				choices = append(choices, o)
+				break
+			}
+		}
+	}
+
+	return choices
 }
 
 // GetResult returns a slice of the Poll options with the most votes
@@ -63,11 +368,15 @@ func (p Poll) GetResult() []string {
 	mostVotes := 0
 	winningOptions := []string{}
 
-	for o, votes := range p.Votes {
-		if l := len(votes); l > mostVotes {
+	for _, o := range p.Options {
+		l := p.Count(o)
+		if l == 0 {
+			continue
+		}
+		if l > mostVotes {
 			winningOptions = []string{o}
 			mostVotes = l
-		} else if l := len(votes); l == mostVotes {
+		} else if l == mostVotes {
 			winningOptions = append(winningOptions, o)
 		}
 	}
@@ -1,9 +1,10 @@
 package poll
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -18,18 +19,18 @@ func TestNewPoll(t *testing.T) {
 			[]string{"yes", "no"},
 			true,
 			nil,
-			&Poll{[]string{"yes", "no"}, make(map[string][]Vote)},
+			&Poll{Options: []string{"yes", "no"}, Votes: make(map[string][]Vote)},
 		},
 		{
 			[]string{},
 			false,
-			errors.New("must supply at least two options"),
+			ErrTooFewOptions,
 			nil,
 		},
 		{
 			[]string{"yes"},
 			false,
-			errors.New("must supply at least two options"),
+			ErrTooFewOptions,
 			nil,
 		},
 	}
@@ -40,6 +41,8 @@ func TestNewPoll(t *testing.T) {
 		if err != nil {
 			if test.ok {
 				t.Errorf("NewPoll returned unexpected error: %v", err)
+			} else if err != test.err {
+				t.Errorf("NewPoll returned incorrect error.\nGot: %v\nWant: %v", err, test.err)
 			}
 		} else {
 			if !test.ok {
@@ -64,35 +67,35 @@ func TestVote(t *testing.T) {
 		expected *Poll
 	}{
 		{
-			&Poll{[]string{"yes", "no"}, make(map[string][]Vote)},
+			&Poll{Options: []string{"yes", "no"}, Votes: make(map[string][]Vote)},
 			"yes",
 			"testuser",
 			true,
 			nil,
 			&Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"yes": []Vote{Vote{"yes", "testuser"}},
 				},
 			},
 		},
 		{
-			&Poll{[]string{"yes", "no"}, make(map[string][]Vote)},
+			&Poll{Options: []string{"yes", "no"}, Votes: make(map[string][]Vote)},
 			"no",
 			"testuser",
 			true,
 			nil,
 			&Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"no": []Vote{Vote{"no", "testuser"}},
 				},
 			},
 		},
 		{
 			&Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"yes": []Vote{Vote{"yes", "testuser1"}},
 				},
 			},
@@ -101,16 +104,16 @@ func TestVote(t *testing.T) {
 			true,
 			nil,
 			&Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"yes": []Vote{Vote{"yes", "testuser1"}, Vote{"yes", "testuser2"}},
 				},
 			},
 		},
 		{
 			&Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"yes": []Vote{Vote{"yes", "testuser1"}, Vote{"yes", "testuser4"}},
 					"no":  []Vote{Vote{"no", "testuser2"}, Vote{"no", "testuser3"}},
 				},
@@ -120,8 +123,8 @@ func TestVote(t *testing.T) {
 			true,
 			nil,
 			&Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"yes": []Vote{
 						Vote{"yes", "testuser1"},
 						Vote{"yes", "testuser4"},
@@ -132,31 +135,66 @@ func TestVote(t *testing.T) {
 			},
 		},
 		{
-			&Poll{[]string{"yes", "no"}, make(map[string][]Vote)},
+			&Poll{Options: []string{"yes", "no"}, Votes: make(map[string][]Vote)},
 			"y",
 			"testuser",
 			false,
-			errors.New("unknown option for this poll"),
-			&Poll{[]string{"yes", "no"}, make(map[string][]Vote)},
+			ErrUnknownOption,
+			&Poll{Options: []string{"yes", "no"}, Votes: make(map[string][]Vote)},
 		},
 		{
 			&Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"yes": []Vote{Vote{"yes", "testuser"}},
 				},
 			},
 			"no",
 			"testuser",
 			false,
-			errors.New("this voter already voted on this poll"),
+			ErrAlreadyVoted,
 			&Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"yes": []Vote{Vote{"yes", "testuser"}},
 				},
 			},
 		},
+		{
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser"}},
+				},
+				MultiChoice: true,
+			},
+			"no",
+			"testuser",
+			true,
+			nil,
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser"}},
+					"no":  []Vote{Vote{"no", "testuser"}},
+				},
+				MultiChoice: true,
+			},
+		},
+		{
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser"}},
+				},
+				MultiChoice: true,
+			},
+			"yes",
+			"testuser",
+			false,
+			ErrAlreadyVoted,
+			nil,
+		},
 	}
 
 	for _, test := range tests {
@@ -165,6 +203,8 @@ func TestVote(t *testing.T) {
 		if err != nil {
 			if test.ok {
 				t.Errorf("Vote returned unexpected error: %v", err)
+			} else if err != test.err {
+				t.Errorf("Vote returned incorrect error.\nGot: %v\nWant: %v", err, test.err)
 			}
 		} else {
 			if !test.ok {
@@ -179,6 +219,237 @@ func TestVote(t *testing.T) {
 	}
 }
 
+func TestUnvote(t *testing.T) {
+	tests := []struct {
+		poll     *Poll
+		option   string
+		voter    string
+		err      error
+		expected *Poll
+	}{
+		{
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser1"}, Vote{"yes", "testuser2"}},
+				},
+			},
+			"yes",
+			"testuser1",
+			nil,
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser2"}},
+				},
+			},
+		},
+		{
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser"}},
+				},
+			},
+			"yes",
+			"testuser",
+			nil,
+			&Poll{Options: []string{"yes", "no"}, Votes: make(map[string][]Vote)},
+		},
+		{
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser"}},
+				},
+			},
+			"no",
+			"testuser",
+			ErrNotVoted,
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser"}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		err := test.poll.Unvote(test.option, test.voter)
+
+		if err != test.err {
+			t.Errorf("Unvote returned incorrect error.\nGot: %v\nWant: %v", err, test.err)
+		}
+
+		if !reflect.DeepEqual(*test.expected, *test.poll) {
+			t.Errorf("Unvote didn't update poll correctly.\nGot: %+v\nWant: %+v",
+				test.poll, test.expected)
+		}
+	}
+}
+
+func TestChoices(t *testing.T) {
+	p := Poll{
+		Options: []string{"yes", "no", "maybe"},
+		Votes: map[string][]Vote{
+			"maybe": []Vote{Vote{"maybe", "testuser1"}},
+			"yes":   []Vote{Vote{"yes", "testuser1"}, Vote{"yes", "testuser2"}},
+		},
+		MultiChoice: true,
+	}
+
+	tests := []struct {
+		voter    string
+		expected []string
+	}{
+		{"testuser1", []string{"yes", "maybe"}},
+		{"testuser2", []string{"yes"}},
+		{"testuser3", []string{}},
+	}
+
+	for _, test := range tests {
+		got := p.Choices(test.voter)
+
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Choices(%q) returned incorrect options\nGot: %q\nWant: %q",
+				test.voter, got, test.expected)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	p := &Poll{
+		Options: []string{"yes", "no"},
+		Votes: map[string][]Vote{
+			"yes": []Vote{Vote{"yes", "testuser1"}},
+		},
+	}
+
+	c := p.Copy()
+	if !reflect.DeepEqual(*p, *c) {
+		t.Errorf("Copy returned a different poll.\nGot: %+v\nWant: %+v", c, p)
+	}
+
+	p.Vote("yes", "testuser2")
+	p.Options[1] = "maybe"
+	if len(c.Votes["yes"]) != 1 || c.Options[1] != "no" {
+		t.Errorf("Copy shares data with the original poll: %+v", c)
+	}
+}
+
+func TestCast(t *testing.T) {
+	tests := []struct {
+		multi   bool
+		ballots [][]string
+		err     error
+		counts  map[string]int
+	}{
+		{
+			false,
+			[][]string{{"yes"}},
+			nil,
+			map[string]int{"yes": 1},
+		},
+		{
+			true,
+			[][]string{{"yes", "no"}},
+			nil,
+			map[string]int{"yes": 1, "no": 1},
+		},
+		{
+			false,
+			[][]string{{"yes"}, {"no"}},
+			ErrAlreadyVoted,
+			map[string]int{"yes": 1},
+		},
+		{
+			false,
+			[][]string{{"yes", "no"}},
+			ErrAlreadyVoted,
+			map[string]int{},
+		},
+		{
+			true,
+			[][]string{{"yes", "yes"}},
+			ErrAlreadyVoted,
+			map[string]int{},
+		},
+		{
+			false,
+			[][]string{{"maybe"}},
+			ErrUnknownOption,
+			map[string]int{},
+		},
+		{
+			false,
+			[][]string{{}},
+			ErrNoChoice,
+			map[string]int{},
+		},
+	}
+
+	for _, test := range tests {
+		p, err := NewAnonymousPoll([]string{"yes", "no"})
+		if err != nil {
+			t.Fatalf("NewAnonymousPoll returned unexpected error: %v", err)
+		}
+		p.MultiChoice = test.multi
+
+		for _, b := range test.ballots {
+			err = p.Cast("testuser", b...)
+		}
+
+		if err != test.err {
+			t.Errorf("Cast returned incorrect error.\nGot: %v\nWant: %v", err, test.err)
+		}
+		if !reflect.DeepEqual(p.Counts, test.counts) {
+			t.Errorf("Cast counted incorrectly.\nGot: %v\nWant: %v", p.Counts, test.counts)
+		}
+	}
+}
+
+func TestAnonymous(t *testing.T) {
+	p, err := NewAnonymousPoll([]string{"yes", "no"})
+	if err != nil {
+		t.Fatalf("NewAnonymousPoll returned unexpected error: %v", err)
+	}
+
+	p.Vote("yes", "testuser1")
+	p.Vote("yes", "testuser2")
+	p.Vote("no", "testuser3")
+
+	if p.Count("yes") != 2 || p.Count("no") != 1 {
+		t.Errorf("Count returned incorrect counts: %d, %d", p.Count("yes"), p.Count("no"))
+	}
+	if !p.HasVoted("testuser1") || p.HasVoted("testuser4") {
+		t.Errorf("HasVoted didn't recognise who voted")
+	}
+	if c := p.Choices("testuser1"); len(c) != 0 {
+		t.Errorf("Choices revealed an anonymous vote: %q", c)
+	}
+	if err := p.Unvote("yes", "testuser1"); err != ErrAnonymous {
+		t.Errorf("Unvote returned incorrect error.\nGot: %v\nWant: %v", err, ErrAnonymous)
+	}
+	if fmt.Sprintf("%q", p.GetResult()) != fmt.Sprintf("%q", []string{"yes"}) {
+		t.Errorf("GetResult didn't return correct result: %q", p.GetResult())
+	}
+
+	stored, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("failed to marshal poll: %v", err)
+	}
+	if strings.Contains(string(stored), "testuser") {
+		t.Errorf("stored poll contains a voter ID: %s", stored)
+	}
+
+	c := p.Copy()
+	c.Vote("no", "testuser4")
+	if p.Count("no") != 1 || p.HasVoted("testuser4") {
+		t.Errorf("Copy shares data with the original poll")
+	}
+}
+
 func TestGetResult(t *testing.T) {
 	tests := []struct {
 		poll     Poll
@@ -186,15 +457,15 @@ func TestGetResult(t *testing.T) {
 	}{
 		{
 			Poll{
-				[]string{"yes", "no"},
-				map[string][]Vote{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
 					"yes": []Vote{Vote{"yes", "testuser"}},
 				},
 			},
 			[]string{"yes"},
 		},
 		{
-			Poll{[]string{"yes", "no"}, make(map[string][]Vote)},
+			Poll{Options: []string{"yes", "no"}, Votes: make(map[string][]Vote)},
 			[]string{},
 		},
 	}
@@ -208,3 +479,191 @@ func TestGetResult(t *testing.T) {
 		}
 	}
 }
+
+func TestRenameOption(t *testing.T) {
+	tests := []struct {
+		option   string
+		name     string
+		err      error
+		expected *Poll
+	}{
+		{
+			"yse", "yes", nil,
+			&Poll{
+				Options: []string{"yes", "no"},
+				Votes: map[string][]Vote{
+					"yes": []Vote{Vote{"yes", "testuser1"}},
+					"no":  []Vote{Vote{"no", "testuser2"}},
+				},
+			},
+		},
+		{
+			"yse", "no", ErrDuplicateOption,
+			&Poll{
+				Options: []string{"yse", "no"},
+				Votes: map[string][]Vote{
+					"yse": []Vote{Vote{"yse", "testuser1"}},
+					"no":  []Vote{Vote{"no", "testuser2"}},
+				},
+			},
+		},
+		{
+			"maybe", "perhaps", ErrUnknownOption,
+			&Poll{
+				Options: []string{"yse", "no"},
+				Votes: map[string][]Vote{
+					"yse": []Vote{Vote{"yse", "testuser1"}},
+					"no":  []Vote{Vote{"no", "testuser2"}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		p := &Poll{
+			Options: []string{"yse", "no"},
+			Votes: map[string][]Vote{
+				"yse": []Vote{Vote{"yse", "testuser1"}},
+				"no":  []Vote{Vote{"no", "testuser2"}},
+			},
+		}
+		err := p.RenameOption(test.option, test.name)
+
+		if err != test.err {
+			t.Errorf("RenameOption(%q, %q) returned incorrect error.\nGot: %v\nWant: %v",
+				test.option, test.name, err, test.err)
+		}
+
+		if !reflect.DeepEqual(*test.expected, *p) {
+			t.Errorf("RenameOption(%q, %q) didn't update poll correctly.\nGot: %+v\nWant: %+v",
+				test.option, test.name, p, test.expected)
+		}
+	}
+}
+
+func TestAddOption(t *testing.T) {
+	p, _ := NewPoll([]string{"yes", "no"})
+
+	if err := p.AddOption("maybe"); err != nil {
+		t.Errorf("AddOption returned an error: %v", err)
+	}
+	if err := p.AddOption("no"); err != ErrDuplicateOption {
+		t.Errorf("AddOption of an existing option returned %v, want %v", err, ErrDuplicateOption)
+	}
+	if !reflect.DeepEqual(p.Options, []string{"yes", "no", "maybe"}) {
+		t.Errorf("AddOption left options %q", p.Options)
+	}
+	if err := p.Vote("maybe", "testuser"); err != nil {
+		t.Errorf("Vote for an added option returned an error: %v", err)
+	}
+}
+
+func TestRemoveOption(t *testing.T) {
+	tests := []struct {
+		multi    bool
+		option   string
+		moveTo   string
+		err      error
+		voters   []string
+		expected map[string][]Vote
+	}{
+		{
+			false, "maybe", "", nil,
+			[]string{"testuser2"},
+			map[string][]Vote{
+				"yes": []Vote{Vote{"yes", "testuser1"}},
+			},
+		},
+		{
+			false, "maybe", "no", nil,
+			[]string{"testuser2"},
+			map[string][]Vote{
+				"yes": []Vote{Vote{"yes", "testuser1"}},
+				"no":  []Vote{Vote{"no", "testuser2"}},
+			},
+		},
+		{
+			true, "maybe", "yes", nil,
+			[]string{"testuser2", "testuser1"},
+			map[string][]Vote{
+				"yes": []Vote{Vote{"yes", "testuser1"}, Vote{"yes", "testuser2"}},
+			},
+		},
+		{
+			false, "maybe", "maybe", ErrDuplicateOption, nil, nil,
+		},
+		{
+			false, "never", "", ErrUnknownOption, nil, nil,
+		},
+		{
+			false, "maybe", "never", ErrUnknownOption, nil, nil,
+		},
+	}
+
+	for _, test := range tests {
+		p := &Poll{
+			Options: []string{"yes", "no", "maybe"},
+			Votes: map[string][]Vote{
+				"yes":   []Vote{Vote{"yes", "testuser1"}},
+				"maybe": []Vote{Vote{"maybe", "testuser2"}},
+			},
+			MultiChoice: test.multi,
+		}
+		if test.multi {
+			p.Votes["maybe"] = append(p.Votes["maybe"], Vote{"maybe", "testuser1"})
+		}
+		before := p.Copy()
+
+		voters, err := p.RemoveOption(test.option, test.moveTo)
+
+		if err != test.err {
+			t.Errorf("RemoveOption(%q, %q) returned incorrect error.\nGot: %v\nWant: %v",
+				test.option, test.moveTo, err, test.err)
+		}
+		if err != nil {
+			if !reflect.DeepEqual(*before, *p) {
+				t.Errorf("RemoveOption(%q, %q) changed the poll when it failed: %+v",
+					test.option, test.moveTo, p)
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(voters, test.voters) {
+			t.Errorf("RemoveOption(%q, %q) returned incorrect voters\nGot: %q\nWant: %q",
+				test.option, test.moveTo, voters, test.voters)
+		}
+		if !reflect.DeepEqual(p.Options, []string{"yes", "no"}) || !reflect.DeepEqual(p.Votes, test.expected) {
+			t.Errorf("RemoveOption(%q, %q) didn't update poll correctly.\nGot: %+v\nWant votes: %+v",
+				test.option, test.moveTo, p, test.expected)
+		}
+	}
+
+	p, _ := NewPoll([]string{"yes", "no"})
+	if _, err := p.RemoveOption("no", ""); err != ErrTooFewOptions {
+		t.Errorf("RemoveOption down to one option returned %v, want %v", err, ErrTooFewOptions)
+	}
+}
+
+func TestRemoveOptionAnonymous(t *testing.T) {
+	p, _ := NewAnonymousPoll([]string{"yes", "no", "maybe"})
+	p.Cast("testuser1", "maybe")
+	p.Cast("testuser2", "yes")
+
+	voters, err := p.RemoveOption("maybe", "yes")
+	if err != nil || len(voters) != 0 {
+		t.Fatalf("RemoveOption returned %q, %v", voters, err)
+	}
+	if p.Count("yes") != 2 || p.Count("maybe") != 0 || !p.HasVoted("testuser1") {
+		t.Errorf("RemoveOption didn't move the anonymous votes: %+v", p)
+	}
+
+	m, _ := NewAnonymousPoll([]string{"yes", "no", "maybe"})
+	m.MultiChoice = true
+	m.Cast("testuser1", "yes", "maybe")
+	if _, err := m.RemoveOption("maybe", "yes"); err != ErrAnonymous {
+		t.Errorf("RemoveOption moving multi choice ballots returned %v, want %v", err, ErrAnonymous)
+	}
+	if _, err := m.RemoveOption("maybe", ""); err != nil || m.Count("yes") != 1 {
+		t.Errorf("RemoveOption voiding multi choice ballots returned %v, left %+v", err, m)
+	}
+}
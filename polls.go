@@ -0,0 +1,979 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+const pollColor = 0x43b581
+
+// numberEmoji are the reactions given to options that don't bring their own
+var numberEmoji = []string{
+	"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣",
+	"6️⃣", "7️⃣", "8️⃣", "9️⃣", "\U0001f51f",
+}
+
+// maxReactions is the most distinct reactions discord allows on one message
+const maxReactions = 20
+
+var (
+	customEmojiPattern = regexp.MustCompile(`^<a?:(\w+:\d+)>\s*`)
+	flagPattern        = regexp.MustCompile(`(?:^|\s)--([a-z-]+)(?:=("[^"]*"|\S+))?`)
+	questionPattern    = regexp.MustCompile(`^\s*"([^"]+)"`)
+	// keycapPattern is an emoji like 1️⃣, whose digit isn't a symbol itself
+	keycapPattern = regexp.MustCompile("^[0-9#*]\uFE0F?\u20E3$")
+)
+
+// pollEntry is a poll the bot is running, along with where it was posted
+type pollEntry struct {
+	ID        int      `json:"id"`
+	GuildID   string   `json:"guild_id"`
+	ChannelID string   `json:"channel_id"`
+	MessageID string   `json:"message_id"`
+	CreatorID string   `json:"creator_id"`
+	Question  string   `json:"question"`
+	Emoji     []string `json:"emoji"`
+	Closed    bool     `json:"closed,omitempty"`
+	// Pinned is set while the bot has the poll's message pinned
+	Pinned bool `json:"pinned,omitempty"`
+	// Secret polls are voted on by DMed ballots instead of reactions
+	Secret bool   `json:"secret,omitempty"`
+	RoleID string `json:"role_id,omitempty"`
+	// Eligible lists who may vote, everyone may when it is nil
+	Eligible []string `json:"eligible"`
+	// Roles are given to the voters for the option at the same index, up to
+	// RoleCaps of them when that is above 0
+	Roles    []string `json:"roles,omitempty"`
+	RoleCaps []int    `json:"role_caps,omitempty"`
+	// Rules are checked against each voter's membership as they vote
+	Rules *Eligibility `json:"rules,omitempty"`
+	// VoiceChannelID is the voice channel whose occupants vote, and
+	// VoicePolicy how the voters follow people joining and leaving it
+	VoiceChannelID string `json:"voice_channel_id,omitempty"`
+	VoicePolicy    string `json:"voice_policy,omitempty"`
+	// AutoClose closes the poll as soon as everyone eligible has voted
+	AutoClose bool `json:"auto_close,omitempty"`
+	// ClosesAt is when the poll closes by itself, if it has a deadline
+	ClosesAt time.Time `json:"closes_at,omitempty"`
+	// RemindEvery is how often members who haven't voted are reminded
+	RemindEvery  time.Duration `json:"remind_every,omitempty"`
+	NextReminder time.Time     `json:"next_reminder,omitempty"`
+	// Mirrors are more messages showing the poll, in other channels or guilds
+	Mirrors []pollMirror `json:"mirrors,omitempty"`
+	// Webhooks name the guild's webhooks the results are published to
+	Webhooks []string `json:"webhooks,omitempty"`
+	// Edits are the changes made to the poll while it was open, oldest first
+	Edits []pollEdit `json:"edits,omitempty"`
+	// Sources are the reactions votes were cast with, by sourceKey
+	Sources map[string]voteSource `json:"sources,omitempty"`
+	// MovedEmoji maps the emoji of removed options whose votes were moved to
+	// the option they moved to
+	MovedEmoji map[string]string `json:"moved_emoji,omitempty"`
+	// Chart is the kind of chart attached to the results, if any
+	Chart string     `json:"chart,omitempty"`
+	Poll  *poll.Poll `json:"poll"`
+}
+
+// pollMirror is one of the messages showing a poll
+type pollMirror struct {
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+}
+
+// messages returns every message showing the poll, its own first
+func (e *pollEntry) messages() []pollMirror {
+	msgs := []pollMirror{{e.GuildID, e.ChannelID, e.MessageID}}
+	return append(msgs, e.Mirrors...)
+}
+
+// inGuild reports whether the poll is shown in guildID
+func (e *pollEntry) inGuild(guildID string) bool {
+	for _, msg := range e.messages() {
+		if msg.GuildID == guildID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// snapshot returns a copy of e that stays the same while e changes
+func (e *pollEntry) snapshot() pollEntry {
+	c := *e
+	c.Emoji = append([]string(nil), e.Emoji...)
+	c.Mirrors = append([]pollMirror(nil), e.Mirrors...)
+	c.Webhooks = append([]string(nil), e.Webhooks...)
+	c.Edits = append([]pollEdit(nil), e.Edits...)
+	if e.Sources != nil {
+		c.Sources = make(map[string]voteSource, len(e.Sources))
+		for k, src := range e.Sources {
+			c.Sources[k] = src
+		}
+	}
+	if e.MovedEmoji != nil {
+		c.MovedEmoji = make(map[string]string, len(e.MovedEmoji))
+		for em, o := range e.MovedEmoji {
+			c.MovedEmoji[em] = o
+		}
+	}
+	c.Poll = e.Poll.Copy()
+
+	return c
+}
+
+// canVote reports whether userID is eligible to vote on the poll
+func (e *pollEntry) canVote(userID string) bool {
+	return e.Eligible == nil || contains(e.Eligible, userID)
+}
+
+// optionFor returns the option voted for by reacting with emoji
+func (e *pollEntry) optionFor(emoji string) (string, bool) {
+	for i, em := range e.Emoji {
+		if em == emoji {
+			return e.Poll.Options[i], true
+		}
+	}
+
+	option, ok := e.MovedEmoji[emoji]
+	return option, ok
+}
+
+// pollStore holds every poll and persists them to disk
+type pollStore struct {
+	mu     sync.Mutex
+	file   string
+	NextID int                `json:"next_id"`
+	Polls  map[int]*pollEntry `json:"polls"`
+}
+
+var polls = &pollStore{file: "polls.json", NextID: 1, Polls: make(map[int]*pollEntry)}
+
+// pollSecret derives the keys anonymous polls hash their voters with. It's
+// kept out of polls.json, where the hashes are, so they can't be matched
+// against every member of a guild.
+var pollSecret []byte
+
+// pollKey returns the key anonymous poll id hashes its voters with
+func pollKey(id int) []byte {
+	mac := hmac.New(sha256.New, pollSecret)
+	mac.Write([]byte(strconv.Itoa(id)))
+	return mac.Sum(nil)
+}
+
+// load reads the persisted polls
+func (ps *pollStore) load() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := loadJSON(ps.file, ps); err != nil {
+		return err
+	}
+	for id, e := range ps.Polls {
+		if e.Poll.Anonymous {
+			e.Poll.Key = pollKey(id)
+		}
+	}
+
+	return nil
+}
+
+// Add stores e under a new ID, which it returns
+func (ps *pollStore) Add(e *pollEntry) (int, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	e.ID = ps.NextID
+	ps.NextID++
+	if e.Poll.Anonymous {
+		e.Poll.Key = pollKey(e.ID)
+	}
+	ps.Polls[e.ID] = e
+
+	return e.ID, saveJSON(ps.file, ps)
+}
+
+// Remove forgets the poll id
+func (ps *pollStore) Remove(id int) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	delete(ps.Polls, id)
+
+	return saveJSON(ps.file, ps)
+}
+
+// Update applies f to the poll id and persists the result if f succeeds
+func (ps *pollStore) Update(id int, f func(*pollEntry) error) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	e, ok := ps.Polls[id]
+	if !ok {
+		return newUserError("poll.not_found", id)
+	}
+	if err := f(e); err != nil {
+		return err
+	}
+
+	return saveJSON(ps.file, ps)
+}
+
+// View calls f with the poll id without changing it
+func (ps *pollStore) View(id int, f func(*pollEntry)) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	e, ok := ps.Polls[id]
+	if ok {
+		f(e)
+	}
+
+	return ok
+}
+
+// Each calls f with every poll, without changing them
+func (ps *pollStore) Each(f func(*pollEntry)) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for _, e := range ps.Polls {
+		f(e)
+	}
+}
+
+// FindByMessage returns the ID of the poll posted, or mirrored, as messageID,
+// or 0
+func (ps *pollStore) FindByMessage(messageID string) int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for id, e := range ps.Polls {
+		for _, msg := range e.messages() {
+			if msg.MessageID == messageID {
+				return id
+			}
+		}
+	}
+
+	return 0
+}
+
+// pollSpec is a parsed request to create a poll
+type pollSpec struct {
+	Question  string
+	Options   []string
+	Emoji     []string
+	Multi     bool
+	Chart     string
+	Secret    bool
+	Anonymous bool
+	RoleID    string
+	Remind    time.Duration
+	// Closes is the --closes deadline, read when the poll opens
+	Closes    string
+	AutoClose bool
+	Voice     bool
+	Voters    []string
+	Rules     Eligibility
+	Roles     bool
+	// VoicePolicy is one of voicePolicies
+	VoicePolicy string
+	Webhooks    []string
+}
+
+// pollFlags are the --flags accepted when creating a poll
+var pollFlags = map[string]func(spec *pollSpec, value string) error{
+	"multi": func(spec *pollSpec, value string) (err error) {
+		spec.Multi, err = flagSwitch(value)
+		return err
+	},
+	"secret": func(spec *pollSpec, value string) (err error) {
+		spec.Secret, err = flagSwitch(value)
+		return err
+	},
+	"anonymous": func(spec *pollSpec, value string) (err error) {
+		spec.Anonymous, err = flagSwitch(value)
+		return err
+	},
+	"role": func(spec *pollSpec, value string) error {
+		roleID, ok := parseRole(value)
+		if !ok {
+			return newUserError("poll.invalid_role", value)
+		}
+		spec.RoleID = roleID
+		return nil
+	},
+	"autoclose": func(spec *pollSpec, value string) (err error) {
+		spec.AutoClose, err = flagSwitch(value)
+		return err
+	},
+	"voice": func(spec *pollSpec, value string) (err error) {
+		spec.Voice, err = flagSwitch(value)
+		return err
+	},
+	"voice-policy": func(spec *pollSpec, value string) error {
+		value = strings.ToLower(value)
+		if !voicePolicies[value] {
+			return newUserError("poll.invalid_voice_policy", value)
+		}
+		spec.VoicePolicy = value
+		return nil
+	},
+	"voters": func(spec *pollSpec, value string) error {
+		spec.Voters = parseMentions(value)
+		if len(spec.Voters) == 0 {
+			return newUserError("poll.invalid_voters", value)
+		}
+		return nil
+	},
+	"roles": func(spec *pollSpec, value string) (err error) {
+		spec.Roles, err = flagSwitch(value)
+		return err
+	},
+	"require-role": func(spec *pollSpec, value string) error {
+		roles, ok := parseRoles(value)
+		if !ok {
+			return newUserError("poll.invalid_role", value)
+		}
+		spec.Rules.RequiredRoles = roles
+		return nil
+	},
+	"exclude-role": func(spec *pollSpec, value string) error {
+		roles, ok := parseRoles(value)
+		if !ok {
+			return newUserError("poll.invalid_role", value)
+		}
+		spec.Rules.ExcludedRoles = roles
+		return nil
+	},
+	"min-member-age": func(spec *pollSpec, value string) error {
+		age, ok := parseAge(value)
+		if !ok {
+			return newUserError("poll.invalid_age", value)
+		}
+		spec.Rules.MinMemberAge = age
+		return nil
+	},
+	"min-account-age": func(spec *pollSpec, value string) error {
+		age, ok := parseAge(value)
+		if !ok {
+			return newUserError("poll.invalid_age", value)
+		}
+		spec.Rules.MinAccountAge = age
+		return nil
+	},
+	"closes": func(spec *pollSpec, value string) error {
+		// when it is depends on the guild the poll opens in, so for now
+		// only check it can be read
+		if _, ok := parseDeadline(value, time.Time{}, time.UTC); !ok {
+			return newUserError("poll.invalid_deadline", value)
+		}
+		spec.Closes = value
+		return nil
+	},
+	"remind": func(spec *pollSpec, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < minReminderInterval {
+			return newUserError("poll.invalid_reminder", value)
+		}
+		spec.Remind = d
+		return nil
+	},
+	"webhooks": func(spec *pollSpec, value string) error {
+		spec.Webhooks = []string{}
+		for _, name := range splitArgs(value, ",") {
+			spec.Webhooks = append(spec.Webhooks, strings.ToLower(name))
+		}
+		if len(spec.Webhooks) == 0 {
+			return newUserError("webhook.not_found", value)
+		}
+		return nil
+	},
+	"chart": func(spec *pollSpec, value string) error {
+		if value == "" {
+			value = "bar"
+		}
+		if !chartKinds[value] {
+			return newUserError("poll.unknown_chart", value)
+		}
+		spec.Chart = value
+		return nil
+	},
+}
+
+// parsePollSpec parses `"question" option, option --flag=value`
+func parsePollSpec(raw string) (*pollSpec, error) {
+	spec := &pollSpec{}
+	if err := spec.parse(raw); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// flagSwitch reads the value of an on or off flag, which is on when it's
+// given without one
+func flagSwitch(value string) (bool, error) {
+	if value == "" {
+		return true, nil
+	}
+
+	return parseSwitch(strings.ToLower(value))
+}
+
+// parse applies the flags, question and options in raw on top of spec, so a
+// spec can be built up from more than one definition. Options, if raw has any,
+// replace the ones spec had.
+func (spec *pollSpec) parse(raw string) error {
+	for _, m := range flagPattern.FindAllStringSubmatch(raw, -1) {
+		set, ok := pollFlags[m[1]]
+		if !ok {
+			return newUserError("poll.unknown_flag", m[1])
+		}
+		if err := set(spec, strings.Trim(m[2], `"`)); err != nil {
+			return err
+		}
+	}
+	raw = flagPattern.ReplaceAllString(raw, "")
+
+	if m := questionPattern.FindStringSubmatch(raw); m != nil {
+		spec.Question = strings.TrimSpace(m[1])
+		raw = raw[len(m[0]):]
+	}
+
+	options, optionEmoji := []string{}, []string{}
+	used, seen := make(map[string]bool), make(map[string]bool)
+	for _, option := range splitArgs(raw, ",") {
+		emoji, text := splitEmoji(option)
+		if text == "" {
+			continue
+		}
+		if emoji != "" && used[emoji] {
+			return newUserError("poll.duplicate_emoji", emoji)
+		}
+		// votes are kept by option, so two options can't share a name
+		if seen[strings.ToLower(text)] {
+			return newUserError("poll.duplicate_option", text)
+		}
+		used[emoji], seen[strings.ToLower(text)] = true, true
+		options = append(options, text)
+		optionEmoji = append(optionEmoji, emoji)
+	}
+
+	if len(options) > maxReactions {
+		return newUserError("poll.too_many_options", maxReactions)
+	}
+
+	// options without their own emoji are numbered
+	next := 0
+	for i := range optionEmoji {
+		if optionEmoji[i] != "" {
+			continue
+		}
+		for next < len(numberEmoji) && used[numberEmoji[next]] {
+			next++
+		}
+		if next == len(numberEmoji) {
+			return newUserError("poll.too_many_options", len(numberEmoji))
+		}
+		optionEmoji[i] = numberEmoji[next]
+		next++
+	}
+
+	if len(options) > 0 {
+		spec.Options, spec.Emoji = options, optionEmoji
+	}
+	return nil
+}
+
+// splitEmoji splits a leading custom or unicode emoji off option. Custom emoji
+// are returned in the name:id form the reaction endpoints use.
+func splitEmoji(option string) (string, string) {
+	if m := customEmojiPattern.FindStringSubmatch(option); m != nil {
+		return m[1], strings.TrimSpace(option[len(m[0]):])
+	}
+
+	i := strings.IndexFunc(option, unicode.IsSpace)
+	if i <= 0 {
+		return "", option
+	}
+	if keycapPattern.MatchString(option[:i]) {
+		return option[:i], strings.TrimSpace(option[i:])
+	}
+	for _, r := range option[:i] {
+		if !unicode.In(r, unicode.So, unicode.Sk, unicode.Mn, unicode.Cf) {
+			return "", option
+		}
+	}
+
+	return option[:i], strings.TrimSpace(option[i:])
+}
+
+// emojiText returns how to write a reaction emoji in a message
+func emojiText(emoji string) string {
+	if strings.Contains(emoji, ":") {
+		return "<:" + emoji + ">"
+	}
+
+	return emoji
+}
+
+// barWidth is how many blocks wide the vote bars in a poll message are
+const barWidth = 10
+
+// pollEmbed renders the message a poll is voted on with, showing the votes
+// cast so far
+func pollEmbed(e *pollEntry, lang string) *discordgo.MessageEmbed {
+	total := 0
+	for _, o := range e.Poll.Options {
+		total += e.Poll.Count(o)
+	}
+
+	lines := []string{}
+	for i, o := range e.Poll.Options {
+		n := e.Poll.Count(o)
+		filled, percent := 0, 0
+		if total > 0 {
+			filled = (n*barWidth + total/2) / total
+			percent = (n*100 + total/2) / total
+		}
+
+		votes := catalog.Plural(lang, "votes", n)
+		if _, cap := e.roleFor(o); cap > 0 {
+			votes = catalog.T(lang, "roles.capacity", n, cap)
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %s\n`%s%s` %d%% · %s", emojiText(e.Emoji[i]), o,
+			strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), percent, votes))
+	}
+
+	title := e.Question
+	if title == "" {
+		title = catalog.T(lang, "poll.untitled")
+	}
+
+	footer := catalog.T(lang, "poll.footer", e.ID) + " · " + catalog.Plural(lang, "votes", total)
+	if e.Poll.MultiChoice {
+		footer += " · " + catalog.T(lang, "poll.multi")
+	}
+	if e.Poll.Anonymous {
+		footer += " · " + catalog.T(lang, "poll.anonymous")
+	} else if e.Secret {
+		footer += " · " + catalog.T(lang, "poll.secret")
+	}
+	if e.AutoClose && !e.Closed {
+		footer += " · " + catalog.Plural(lang, "poll.autoclose", len(e.Eligible))
+	}
+	if e.Closed {
+		footer += " · " + catalog.T(lang, "poll.closed")
+	} else if !e.ClosesAt.IsZero() {
+		// discord shows the timestamp after the footer, in the reader's time zone
+		footer += " · " + catalog.T(lang, "poll.closes")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: strings.Join(lines, "\n"),
+		Color:       pollColor,
+		Footer:      &discordgo.MessageEmbedFooter{Text: footer},
+	}
+	if !e.ClosesAt.IsZero() {
+		embed.Timestamp = e.ClosesAt.Format(time.RFC3339)
+	}
+
+	return embed
+}
+
+// pollResults describes the votes cast on a poll so far
+func pollResults(e *pollEntry, lang string) string {
+	type count struct {
+		option string
+		emoji  string
+		votes  int
+	}
+
+	counts := []count{}
+	total := 0
+	for i, o := range e.Poll.Options {
+		n := e.Poll.Count(o)
+		counts = append(counts, count{o, e.Emoji[i], n})
+		total += n
+	}
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].votes > counts[j].votes })
+
+	title := e.Question
+	if title == "" {
+		title = catalog.T(lang, "poll.untitled")
+	}
+
+	lines := []string{catalog.T(lang, "poll.results_title", title)}
+	for _, c := range counts {
+		lines = append(lines, fmt.Sprintf("%s %s: %s", emojiText(c.emoji), c.option,
+			catalog.Plural(lang, "votes", c.votes)))
+	}
+
+	winners := e.Poll.GetResult()
+	switch {
+	case total == 0:
+		lines = append(lines, catalog.T(lang, "poll.no_votes"))
+	case len(winners) == 1:
+		lines = append(lines, catalog.T(lang, "poll.winner", winners[0]))
+	default:
+		sort.Strings(winners)
+		lines = append(lines, catalog.T(lang, "poll.tie", strings.Join(winners, ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// pollSubcommands are the commands run with `poll <subcommand>`
+var pollSubcommands = map[string]HandlerFunc{
+	"close":    handlePollClose,
+	"results":  handlePollResults,
+	"chart":    handlePollChart,
+	"schedule": handlePollSchedule,
+	"mirror":   handlePollMirror,
+	"edit":     handlePollEdit,
+}
+
+func handlePoll(ctx *Context) error {
+	if len(ctx.Args) > 0 {
+		if sub, ok := pollSubcommands[strings.ToLower(ctx.Args[0])]; ok {
+			ctx.Raw = strings.TrimSpace(strings.TrimPrefix(ctx.Raw, ctx.Args[0]))
+			ctx.Args = ctx.Args[1:]
+			return sub(ctx)
+		}
+	}
+
+	spec, err := parsePollSpec(ctx.Raw)
+	if err != nil {
+		return err
+	}
+
+	e, err := newPollEntry(ctx.Session, ctx.GuildID(), ctx.Message.ChannelID, ctx.Message.Author.ID, spec)
+	if err != nil {
+		return err
+	}
+
+	return openPoll(ctx.Session, e)
+}
+
+// newPollEntry builds the poll spec describes, created by creatorID in
+// channelID, ready for openPoll
+func newPollEntry(s *discordgo.Session, guildID, channelID, creatorID string, spec *pollSpec) (*pollEntry, error) {
+	// reactions show who voted for what, so anonymous polls are always secret
+	spec.Secret = spec.Secret || spec.Anonymous
+
+	var closesAt time.Time
+	if spec.Closes != "" {
+		var ok bool
+		if closesAt, ok = parseDeadline(spec.Closes, time.Now(), guildLocation(guildID)); !ok {
+			return nil, newUserError("poll.invalid_deadline", spec.Closes)
+		}
+	}
+
+	var roles []string
+	var caps []int
+	if spec.Roles {
+		// everyone can see who holds a role, so picking one can't be secret
+		if spec.Secret {
+			return nil, newUserError("roles.secret")
+		}
+
+		var err error
+		spec.Options, roles, caps, err = roleOptions(s, guildID, spec.Options)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAssignable(s, guildID, channelID, roles); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range spec.Webhooks {
+		if _, ok := webhooks.Get(guildID, name); !ok {
+			return nil, newUserError("webhook.not_found", name)
+		}
+	}
+
+	newPoll := poll.NewPoll
+	if spec.Anonymous {
+		newPoll = poll.NewAnonymousPoll
+	}
+	p, err := newPoll(spec.Options)
+	if err != nil {
+		return nil, err
+	}
+	p.MultiChoice = spec.Multi
+
+	e := &pollEntry{
+		GuildID:   guildID,
+		ChannelID: channelID,
+		CreatorID: creatorID,
+		Question:  spec.Question,
+		Emoji:     spec.Emoji,
+		Chart:     spec.Chart,
+		Secret:    spec.Secret,
+		RoleID:    spec.RoleID,
+		ClosesAt:  closesAt,
+		AutoClose: spec.AutoClose,
+		Roles:     roles,
+		RoleCaps:  caps,
+		Webhooks:  spec.Webhooks,
+		Poll:      p,
+	}
+	if !spec.Rules.empty() {
+		rules := spec.Rules
+		e.Rules = &rules
+	}
+	if spec.Remind > 0 {
+		e.RemindEvery = spec.Remind
+		e.NextReminder = time.Now().Add(spec.Remind)
+	}
+
+	e.Eligible, err = pollVoters(s, e, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// pollVoters returns who may vote on poll e, fixed when it is created, or nil
+// if anyone who can see it may
+func pollVoters(s *discordgo.Session, e *pollEntry, spec *pollSpec) ([]string, error) {
+	// a voice poll's voters are whoever is in the channel
+	if spec.Voice && len(spec.Voters) > 0 {
+		return nil, newUserError("poll.voice_voters")
+	}
+
+	var voters []string
+	var err error
+	switch {
+	case len(spec.Voters) > 0:
+		voters = spec.Voters
+	case spec.Voice:
+		e.VoiceChannelID, voters, err = voiceOccupants(s, e.GuildID, e.CreatorID)
+		e.VoicePolicy = spec.VoicePolicy
+		if e.VoicePolicy == "" {
+			e.VoicePolicy = "fixed"
+		}
+	case spec.Secret || spec.RoleID != "" || spec.AutoClose:
+		voters, err = eligibleVoters(s, e.GuildID, e.ChannelID, spec.RoleID, e.Rules)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(voters) == 0 {
+		return nil, newUserError("poll.no_voters")
+	}
+	return voters, nil
+}
+
+// openPoll stores e, posts its message and seeds the message with a reaction
+// for each option
+func openPoll(s *discordgo.Session, e *pollEntry) error {
+	id, err := polls.Add(e)
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.ChannelMessageSendEmbed(e.ChannelID, pollEmbed(e, language(e.GuildID, "")))
+	if err != nil {
+		polls.Remove(id)
+		return err
+	}
+
+	err = polls.Update(id, func(e *pollEntry) error {
+		e.MessageID = msg.ID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if e.Secret {
+		go sendBallots(s, id)
+	} else {
+		for _, emoji := range e.Emoji {
+			if err := s.MessageReactionAdd(msg.ChannelID, msg.ID, emoji); err != nil {
+				fmt.Printf("failed to add %s to poll %d: %v\n", emoji, id, err)
+			}
+		}
+	}
+
+	if err := pinPoll(s, id); err != nil {
+		fmt.Printf("failed to pin poll %d: %v\n", id, err)
+	}
+
+	if !e.ClosesAt.IsZero() {
+		deadlines.Schedule(s, id, e.ClosesAt)
+	}
+
+	fmt.Printf("opened poll %d in %s\n", id, e.ChannelID)
+
+	return nil
+}
+
+// pollArg parses the poll ID a subcommand was given
+func pollArg(ctx *Context) (int, error) {
+	if len(ctx.Args) == 0 {
+		return 0, &UsageError{ctx.Command}
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(ctx.Args[0], "#"))
+	if err != nil {
+		return 0, newUserError("poll.not_found", ctx.Args[0])
+	}
+
+	return id, nil
+}
+
+// canManagePoll reports whether the invoking user created the poll or may
+// manage messages in its channel
+func canManagePoll(ctx *Context, e *pollEntry) bool {
+	if e.CreatorID == ctx.Message.Author.ID {
+		return true
+	}
+
+	ok, err := hasPermissions(ctx.Session, ctx.Message.Author.ID, e.ChannelID, discordgo.PermissionManageMessages)
+	return err == nil && ok
+}
+
+// checkManagePoll returns an error unless poll id is in the invoking guild and
+// the invoking user can manage it. It looks at a snapshot of the poll, so
+// checking permissions never holds up the poll store.
+func checkManagePoll(ctx *Context, id int) error {
+	var e pollEntry
+	found := polls.View(id, func(entry *pollEntry) { e = entry.snapshot() })
+	if !found || e.GuildID != ctx.GuildID() {
+		return newUserError("poll.not_found", id)
+	}
+	if !canManagePoll(ctx, &e) {
+		return &PermissionError{ctx.Command}
+	}
+
+	return nil
+}
+
+func handlePollClose(ctx *Context) error {
+	id, err := pollArg(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkManagePoll(ctx, id); err != nil {
+		return err
+	}
+
+	err = polls.Update(id, func(e *pollEntry) error {
+		if e.Closed {
+			return newUserError("poll.already_closed", id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return closePoll(ctx.Session, id)
+}
+
+// closePoll stops the poll id from taking votes and announces its results
+func closePoll(s *discordgo.Session, id int) error {
+	var e pollEntry
+	err := polls.Update(id, func(entry *pollEntry) error {
+		if entry.Closed {
+			return newUserError("poll.already_closed", id)
+		}
+		entry.Closed = true
+		e = entry.snapshot()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	deadlines.Cancel(id)
+
+	for _, msg := range e.messages() {
+		lang := language(msg.GuildID, "")
+		if _, err := s.ChannelMessageEditEmbed(msg.ChannelID, msg.MessageID, pollEmbed(&e, lang)); err != nil {
+			fmt.Printf("failed to mark poll %d closed: %v\n", id, err)
+		}
+	}
+
+	if err := unpinPoll(s, id); err != nil {
+		fmt.Printf("failed to unpin poll %d: %v\n", id, err)
+	}
+
+	refresher.Forget(id)
+	fmt.Printf("closed poll %d\n", id)
+
+	publishResults(s, &e)
+
+	for _, msg := range e.Mirrors {
+		if err := sendPollResults(s, msg.ChannelID, &e, language(msg.GuildID, "")); err != nil {
+			fmt.Printf("failed to post results of poll %d to a mirror: %v\n", id, err)
+		}
+	}
+
+	return sendPollResults(s, e.ChannelID, &e, language(e.GuildID, ""))
+}
+
+// autoClosePoll closes poll id if it closes once everyone eligible has voted
+// and they all have
+func autoClosePoll(s *discordgo.Session, id int) {
+	done := false
+	polls.View(id, func(e *pollEntry) {
+		// a voice poll everyone left waits for people to join, or for its
+		// deadline
+		if !e.AutoClose || e.Closed || len(e.Eligible) == 0 {
+			return
+		}
+		for _, user := range e.Eligible {
+			if !e.Poll.HasVoted(user) {
+				return
+			}
+		}
+		done = true
+	})
+	if !done {
+		return
+	}
+
+	err := closePoll(s, id)
+	if _, closed := err.(*userError); err != nil && !closed {
+		fmt.Printf("failed to close poll %d once everyone voted: %v\n", id, err)
+	}
+}
+
+func handlePollResults(ctx *Context) error {
+	id, err := pollArg(ctx)
+	if err != nil {
+		return err
+	}
+
+	var e pollEntry
+	found := polls.View(id, func(entry *pollEntry) {
+		e = entry.snapshot()
+	})
+	if !found || !e.inGuild(ctx.GuildID()) {
+		return newUserError("poll.not_found", id)
+	}
+
+	return sendPollResults(ctx.Session, ctx.Message.ChannelID, &e, ctx.Lang())
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePollSpec(t *testing.T) {
+	tests := []struct {
+		raw      string
+		err      error
+		expected *pollSpec
+	}{
+		{
+			`"Lunch?" pizza, tacos`,
+			nil,
+			&pollSpec{Question: "Lunch?", Options: []string{"pizza", "tacos"}, Emoji: []string{"1️⃣", "2️⃣"}},
+		},
+		{
+			`"Lunch?" 🍕 pizza, tacos, <:sushi:123> sushi`,
+			nil,
+			&pollSpec{
+				Question: "Lunch?",
+				Options:  []string{"pizza", "tacos", "sushi"},
+				Emoji:    []string{"🍕", "1️⃣", "sushi:123"},
+			},
+		},
+		{
+			`"Lunch?" pizza, 1️⃣ tacos, sushi`,
+			nil,
+			&pollSpec{Question: "Lunch?", Options: []string{"pizza", "tacos", "sushi"}, Emoji: []string{"2️⃣", "1️⃣", "3️⃣"}},
+		},
+		{
+			`pizza, , tacos`,
+			nil,
+			&pollSpec{Options: []string{"pizza", "tacos"}, Emoji: []string{"1️⃣", "2️⃣"}},
+		},
+		{
+			`"Lunch?" pizza, tacos --multi --chart`,
+			nil,
+			&pollSpec{Question: "Lunch?", Options: []string{"pizza", "tacos"}, Emoji: []string{"1️⃣", "2️⃣"}, Multi: true, Chart: "bar"},
+		},
+		// anonymous polls are made secret once every flag is read
+		{
+			`"Lunch?" pizza, tacos --anonymous --secret=off`,
+			nil,
+			&pollSpec{Question: "Lunch?", Options: []string{"pizza", "tacos"}, Emoji: []string{"1️⃣", "2️⃣"}, Anonymous: true},
+		},
+		{`"Lunch?" 🍕 pizza, 🍕 calzone`, newUserError("poll.duplicate_emoji", "🍕"), nil},
+		{`"Lunch?" pizza, tacos, Pizza`, newUserError("poll.duplicate_option", "Pizza"), nil},
+		{`"Lunch?" 🍕 pizza, 🌮 pizza`, newUserError("poll.duplicate_option", "pizza"), nil},
+		{`"Lunch?" pizza, tacos --bogus`, newUserError("poll.unknown_flag", "bogus"), nil},
+		{`"Lunch?" pizza, tacos --multi=maybe`, newUserError("config.invalid_switch", "maybe"), nil},
+		{`"Lunch?" pizza, tacos --chart=line`, newUserError("poll.unknown_chart", "line"), nil},
+		{`a, b, c, d, e, f, g, h, i, j, k`, newUserError("poll.too_many_options", 10), nil},
+		{manyOptions(maxReactions + 1), newUserError("poll.too_many_options", maxReactions), nil},
+	}
+
+	for _, test := range tests {
+		spec, err := parsePollSpec(test.raw)
+
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("parsePollSpec(%q) returned incorrect error.\nGot: %v\nWant: %v", test.raw, err, test.err)
+		}
+		if !reflect.DeepEqual(spec, test.expected) {
+			t.Errorf("parsePollSpec(%q) returned incorrect spec.\nGot: %+v\nWant: %+v", test.raw, spec, test.expected)
+		}
+	}
+}
+
+// manyOptions returns n options that each bring their own emoji
+func manyOptions(n int) string {
+	options := []string{}
+	for i := 0; i < n; i++ {
+		options = append(options, fmt.Sprintf("<:e%d:%d> option %d", i, i, i))
+	}
+
+	return strings.Join(options, ", ")
+}
+
+func TestSplitEmoji(t *testing.T) {
+	tests := []struct {
+		option string
+		emoji  string
+		text   string
+	}{
+		{"pizza", "", "pizza"},
+		{"extra cheese", "", "extra cheese"},
+		{"🍕 pizza", "🍕", "pizza"},
+		{"🍕pizza", "", "🍕pizza"},
+		{"❤️ love", "❤️", "love"},
+		{"👍🏽 yes", "👍🏽", "yes"},
+		{"🇫🇷 France", "🇫🇷", "France"},
+		{"1️⃣ first", "1️⃣", "first"},
+		{"1st place", "", "1st place"},
+		{"<:sushi:123> sushi", "sushi:123", "sushi"},
+		{"<a:dance:456>party", "dance:456", "party"},
+		{"<:sushi:123>", "sushi:123", ""},
+	}
+
+	for _, test := range tests {
+		emoji, text := splitEmoji(test.option)
+
+		if emoji != test.emoji || text != test.text {
+			t.Errorf("splitEmoji(%q) returned (%q, %q)\nWant: (%q, %q)", test.option, emoji, text, test.emoji, test.text)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+var (
+	errPollClosed  = errors.New("poll is closed")
+	errNotEligible = errors.New("voter isn't eligible for this poll")
+)
+
+func handleReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.UserID == botID {
+		return
+	}
+
+	id := polls.FindByMessage(r.MessageID)
+	if id == 0 {
+		return
+	}
+
+	emoji := r.Emoji.APIName()
+
+	var e pollEntry
+	polls.View(id, func(entry *pollEntry) { e = entry.snapshot() })
+	if err := checkVoter(s, &e, r.UserID); err != nil && !e.Closed {
+		if ineligible, ok := err.(*IneligibleError); ok {
+			explainIneligible(s, &e, r.UserID, ineligible)
+			if err := s.MessageReactionRemove(r.ChannelID, r.MessageID, emoji, r.UserID); err != nil {
+				fmt.Printf("failed to remove reaction from poll %d: %v\n", id, err)
+			}
+			return
+		}
+		fmt.Printf("failed to check eligibility on poll %d: %v\n", id, err)
+		return
+	}
+
+	var option string
+	var swapped []string
+	err := polls.Update(id, func(e *pollEntry) error {
+		var err error
+		option, swapped, err = e.reactionVote(voteSource{r.ChannelID, r.MessageID, emoji}, r.UserID)
+		return err
+	})
+
+	switch err {
+	case nil:
+		if e.Roles != nil {
+			updateRoles(s, &e, r.UserID, []string{option}, swapped)
+			// the old reactions go too, their votes were already taken back
+			for _, o := range swapped {
+				for i, eo := range e.Poll.Options {
+					if eo != o {
+						continue
+					}
+					if err := s.MessageReactionRemove(r.ChannelID, r.MessageID, e.Emoji[i], r.UserID); err != nil {
+						fmt.Printf("failed to remove reaction from poll %d: %v\n", id, err)
+					}
+				}
+			}
+		}
+		refresher.Refresh(s, id)
+		autoClosePoll(s, id)
+	case errRoleFull:
+		role, _ := e.roleFor(option)
+		explainIneligible(s, &e, r.UserID, &IneligibleError{Rule: "role_full", RoleID: role})
+		if err := s.MessageReactionRemove(r.ChannelID, r.MessageID, emoji, r.UserID); err != nil {
+			fmt.Printf("failed to remove reaction from poll %d: %v\n", id, err)
+		}
+	case errPollClosed, errNotEligible, poll.ErrUnknownOption, poll.ErrAlreadyVoted:
+		// the reaction isn't a vote, so it shouldn't look like one
+		if err := s.MessageReactionRemove(r.ChannelID, r.MessageID, emoji, r.UserID); err != nil {
+			fmt.Printf("failed to remove reaction from poll %d: %v\n", id, err)
+		}
+	default:
+		fmt.Printf("failed to vote on poll %d: %v\n", id, err)
+	}
+}
+
+// explainIneligible tells userID privately why they can't vote on poll e
+func explainIneligible(s *discordgo.Session, e *pollEntry, userID string, err *IneligibleError) {
+	lang := language(e.GuildID, userID)
+	embed := &discordgo.MessageEmbed{
+		Title:       catalog.T(lang, "eligibility.title", e.ID),
+		Description: err.explain(s, e.GuildID, lang),
+		Color:       pollColor,
+	}
+
+	if err := sendDM(s, userID, embed); err != nil {
+		fmt.Printf("failed to explain ineligibility on poll %d: %v\n", e.ID, err)
+	}
+}
+
+func handleReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	if r.UserID == botID {
+		return
+	}
+
+	id := polls.FindByMessage(r.MessageID)
+	if id == 0 {
+		return
+	}
+
+	var e pollEntry
+	var option string
+	err := polls.Update(id, func(entry *pollEntry) error {
+		var err error
+		if option, err = entry.reactionUnvote(voteSource{r.ChannelID, r.MessageID, r.Emoji.APIName()}, r.UserID); err != nil {
+			return err
+		}
+		e = entry.snapshot()
+		return nil
+	})
+
+	// removals of reactions that were never votes, including the ones the bot
+	// removed itself and duplicates of a vote cast on another message, are
+	// expected
+	switch err {
+	case nil:
+		if e.Roles != nil {
+			updateRoles(s, &e, r.UserID, nil, []string{option})
+		}
+		refresher.Refresh(s, id)
+	case errPollClosed, poll.ErrUnknownOption, poll.ErrNotVoted:
+	default:
+		fmt.Printf("failed to unvote on poll %d: %v\n", id, err)
+	}
+}
+
+// voteSource is the reaction a vote was cast with
+type voteSource struct {
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+// sourceKey keys the source of voter's vote for option in pollEntry.Sources,
+// user IDs never hold a colon
+func sourceKey(option, voter string) string {
+	return voter + ":" + option
+}
+
+// setSource records src as the reaction voter's vote for option was cast with
+func (e *pollEntry) setSource(option, voter string, src voteSource) {
+	if e.Sources == nil {
+		e.Sources = make(map[string]voteSource)
+	}
+	e.Sources[sourceKey(option, voter)] = src
+}
+
+// containsSource reports whether srcs holds src
+func containsSource(srcs []voteSource, src voteSource) bool {
+	for _, s := range srcs {
+		if s == src {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reactionVote casts voter's vote for the option reacting with src stands
+// for, returning the option and, on a role poll, the options it took back
+func (e *pollEntry) reactionVote(src voteSource, voter string) (string, []string, error) {
+	if e.Closed || e.Secret {
+		return "", nil, errPollClosed
+	}
+	if !e.canVote(voter) {
+		return "", nil, errNotEligible
+	}
+
+	option, ok := e.optionFor(src.Emoji)
+	if !ok {
+		return "", nil, poll.ErrUnknownOption
+	}
+
+	var swapped []string
+	if e.Roles != nil {
+		var err error
+		if swapped, err = e.roleVote(option, voter); err != nil {
+			return option, nil, err
+		}
+		for _, o := range swapped {
+			delete(e.Sources, sourceKey(o, voter))
+		}
+	}
+
+	if err := e.Poll.Vote(option, voter); err != nil {
+		return option, nil, err
+	}
+	e.setSource(option, voter, src)
+
+	return option, swapped, nil
+}
+
+// reactionUnvote takes back voter's vote for the option src stands for, but
+// only when src is the reaction the vote was cast with: the same vote can't be
+// cast twice from two messages or with a moved option's emoji, and removing
+// the reaction that was turned away mustn't take back the one that counts
+func (e *pollEntry) reactionUnvote(src voteSource, voter string) (string, error) {
+	if e.Closed || e.Secret {
+		return "", errPollClosed
+	}
+
+	option, ok := e.optionFor(src.Emoji)
+	if !ok {
+		return "", poll.ErrUnknownOption
+	}
+
+	key := sourceKey(option, voter)
+	// votes from before sources were kept count for any of their reactions
+	if cast, ok := e.Sources[key]; ok && (cast.MessageID != src.MessageID || cast.Emoji != src.Emoji) {
+		return option, poll.ErrNotVoted
+	}
+
+	if err := e.Poll.Unvote(option, voter); err != nil {
+		return option, err
+	}
+	delete(e.Sources, key)
+
+	return option, nil
+}
+
+// moveSources moves the sources of the votes for option to moveTo, or
+// forgets them if moveTo is empty, before the votes themselves move. Voters
+// who also voted for moveTo keep the source of that vote.
+func (e *pollEntry) moveSources(option, moveTo string) {
+	for _, v := range e.Poll.Votes[option] {
+		key := sourceKey(option, v.Voter)
+		src, ok := e.Sources[key]
+		delete(e.Sources, key)
+		if !ok || moveTo == "" {
+			continue
+		}
+		if !contains(e.Poll.Choices(v.Voter), moveTo) {
+			e.Sources[sourceKey(moveTo, v.Voter)] = src
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+func TestReactionUnvote(t *testing.T) {
+	a := voteSource{"c1", "m1", "1️⃣"}
+	b := voteSource{"c2", "m2", "1️⃣"}
+	moved := voteSource{"c1", "m1", "3️⃣"}
+
+	type step struct {
+		remove bool
+		src    voteSource
+		err    error
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+		voted bool
+	}{
+		{
+			"a duplicate on a mirror is turned away without taking back the vote",
+			[]step{
+				{false, a, nil},
+				{false, b, poll.ErrAlreadyVoted},
+				{true, b, poll.ErrNotVoted},
+			},
+			true,
+		},
+		{
+			"removing the reaction the vote was cast with takes it back",
+			[]step{
+				{false, a, nil},
+				{false, b, poll.ErrAlreadyVoted},
+				{true, b, poll.ErrNotVoted},
+				{true, a, nil},
+			},
+			false,
+		},
+		{
+			"a moved vote is taken back by its own reaction",
+			[]step{
+				{false, moved, nil},
+				{false, a, poll.ErrAlreadyVoted},
+				{true, a, poll.ErrNotVoted},
+				{true, moved, nil},
+			},
+			false,
+		},
+		{
+			"a moved vote stays while the destination's reaction is turned away",
+			[]step{
+				{false, moved, nil},
+				{false, a, poll.ErrAlreadyVoted},
+				{true, a, poll.ErrNotVoted},
+			},
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		p, _ := poll.NewPoll([]string{"yes", "no"})
+		e := &pollEntry{
+			Emoji:      []string{"1️⃣", "2️⃣"},
+			MovedEmoji: map[string]string{"3️⃣": "yes"},
+			Poll:       p,
+		}
+
+		for i, st := range test.steps {
+			var err error
+			if st.remove {
+				_, err = e.reactionUnvote(st.src, "testuser")
+			} else {
+				_, _, err = e.reactionVote(st.src, "testuser")
+			}
+			if err != st.err {
+				t.Errorf("%s: step %d returned incorrect error.\nGot: %v\nWant: %v", test.name, i, err, st.err)
+			}
+		}
+
+		if voted := e.Poll.HasVoted("testuser"); voted != test.voted {
+			t.Errorf("%s: HasVoted = %v, want %v", test.name, voted, test.voted)
+		}
+	}
+}
+
+func TestReactionUnvoteWithoutSource(t *testing.T) {
+	p, _ := poll.NewPoll([]string{"yes", "no"})
+	p.Vote("yes", "testuser")
+	e := &pollEntry{Emoji: []string{"1️⃣", "2️⃣"}, Poll: p}
+
+	// votes cast before sources were kept come back with any of their reactions
+	if _, err := e.reactionUnvote(voteSource{"c1", "m1", "1️⃣"}, "testuser"); err != nil {
+		t.Errorf("reactionUnvote returned unexpected error: %v", err)
+	}
+	if e.Poll.HasVoted("testuser") {
+		t.Errorf("reactionUnvote left the vote in place")
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+// reactionPageSize is the most users discord returns for one page of reactions
+const reactionPageSize = 100
+
+// reconcileMu stops a Resumed from reconciling while a Ready still is
+var reconcileMu sync.Mutex
+
+func handleReady(s *discordgo.Session, r *discordgo.Ready) {
+	reconcilePolls(s)
+}
+
+func handleResumed(s *discordgo.Session, r *discordgo.Resumed) {
+	reconcilePolls(s)
+}
+
+// reactionUsers returns every user who reacted to a message with emoji.
+// Session.MessageReactions only returns the first page, so the rest are
+// requested with the after parameter it doesn't expose.
+func reactionUsers(s *discordgo.Session, channelID, messageID, emoji string) ([]*discordgo.User, error) {
+	users, err := s.MessageReactions(channelID, messageID, emoji, reactionPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for page := users; len(page) == reactionPageSize; {
+		uri := fmt.Sprintf("%s?limit=%d&after=%s", discordgo.EndpointMessageReactions(channelID, messageID, emoji),
+			reactionPageSize, page[len(page)-1].ID)
+		body, err := s.RequestWithBucketID("GET", uri, nil, discordgo.EndpointMessageReaction(channelID, "", "", ""))
+		if err != nil {
+			return nil, err
+		}
+
+		page = nil
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		users = append(users, page...)
+	}
+
+	return users, nil
+}
+
+// reconcilePolls applies the votes and unvotes made by reacting to open polls
+// while the bot wasn't listening
+func reconcilePolls(s *discordgo.Session) {
+	reconcileMu.Lock()
+	defer reconcileMu.Unlock()
+
+	open := []pollEntry{}
+	polls.Each(func(e *pollEntry) {
+		if !e.Closed && !e.Secret && e.MessageID != "" {
+			open = append(open, e.snapshot())
+		}
+	})
+
+	for _, p := range open {
+		reacted := make([]map[string][]voteSource, len(p.Emoji))
+		for i := range reacted {
+			reacted[i] = make(map[string][]voteSource)
+		}
+
+		failed := false
+		for _, msg := range p.messages() {
+			if err := messageReactions(s, &p, msg, reacted); err != nil {
+				fmt.Printf("failed to fetch reactions on poll %d: %v\n", p.ID, err)
+				failed = true
+				break
+			}
+		}
+		if failed {
+			continue
+		}
+
+		if err := reconcilePoll(s, &p, reacted); err != nil {
+			fmt.Printf("failed to reconcile poll %d: %v\n", p.ID, err)
+		}
+	}
+}
+
+// messageReactions adds the reactions on msg, one of the messages of poll p,
+// to reacted under the option each votes for and the user who reacted
+func messageReactions(s *discordgo.Session, p *pollEntry, msg pollMirror, reacted []map[string][]voteSource) error {
+	for i := range p.Emoji {
+		for _, emoji := range p.reactionsFor(i) {
+			users, err := reactionUsers(s, msg.ChannelID, msg.MessageID, emoji)
+			if err != nil {
+				return err
+			}
+
+			for _, u := range users {
+				if u.ID == botID {
+					continue
+				}
+				// reactions from members the rules don't allow to vote
+				// aren't votes
+				if _, ok := checkVoter(s, p, u.ID).(*IneligibleError); ok {
+					if err := s.MessageReactionRemove(msg.ChannelID, msg.MessageID, emoji, u.ID); err != nil {
+						fmt.Printf("failed to remove reaction from poll %d: %v\n", p.ID, err)
+					}
+					continue
+				}
+				reacted[i][u.ID] = append(reacted[i][u.ID], voteSource{msg.ChannelID, msg.MessageID, emoji})
+			}
+		}
+	}
+
+	return nil
+}
+
+// reconcilePoll makes the stored votes of poll p match reacted, the
+// reactions of each user voting for each option of snapshot p. A poll whose
+// options were edited since p was taken is left for the next reconcile, as
+// reacted no longer lines up with them.
+func reconcilePoll(s *discordgo.Session, p *pollEntry, reacted []map[string][]voteSource) error {
+	id := p.ID
+
+	type rejected struct {
+		src  voteSource
+		user string
+	}
+	invalid := []rejected{}
+
+	// roleChange is a role to give or take back after a missed vote on a
+	// role poll
+	type roleChange struct {
+		user   string
+		option string
+	}
+	grants, revokes := []roleChange{}, []roleChange{}
+
+	var snap pollEntry
+	changed, stale := false, false
+	err := polls.Update(id, func(e *pollEntry) error {
+		if e.Closed || !sameStrings(e.Emoji, p.Emoji) {
+			stale = true
+			return nil
+		}
+
+		added := make([]int, len(e.Poll.Options))
+		removed := make([]int, len(e.Poll.Options))
+
+		// removals go first so a voter who switched options while the bot was
+		// away can vote for their new one in a single choice poll
+		for i, o := range e.Poll.Options {
+			for _, v := range append([]poll.Vote(nil), e.Poll.Votes[o]...) {
+				if len(reacted[i][v.Voter]) == 0 {
+					e.Poll.Unvote(o, v.Voter)
+					delete(e.Sources, sourceKey(o, v.Voter))
+					revokes = append(revokes, roleChange{v.Voter, o})
+					removed[i]++
+				}
+			}
+		}
+
+		for i, o := range e.Poll.Options {
+			for user, srcs := range reacted[i] {
+				if contains(e.Poll.Choices(user), o) {
+					// the vote stays, keeping its source if that reaction
+					// is still there
+					if !containsSource(srcs, e.Sources[sourceKey(o, user)]) {
+						e.setSource(o, user, srcs[0])
+					}
+					continue
+				}
+				ok := e.canVote(user)
+				if _, cap := e.roleFor(o); ok && cap > 0 && e.Poll.Count(o) >= cap {
+					ok = false
+				}
+				if ok && e.Poll.Vote(o, user) != nil {
+					ok = false
+				}
+				if !ok {
+					for _, src := range srcs {
+						invalid = append(invalid, rejected{src, user})
+					}
+					continue
+				}
+				e.setSource(o, user, srcs[0])
+				grants = append(grants, roleChange{user, o})
+				added[i]++
+			}
+		}
+
+		for i, o := range e.Poll.Options {
+			if added[i] > 0 || removed[i] > 0 {
+				changed = true
+				fmt.Printf("poll %d option %q: %d missed votes, %d missed unvotes\n", id, o, added[i], removed[i])
+			}
+		}
+
+		snap = e.snapshot()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if stale {
+		fmt.Printf("poll %d changed while its reactions were fetched, skipping it\n", id)
+		return nil
+	}
+
+	if snap.Roles != nil {
+		for _, c := range revokes {
+			updateRoles(s, &snap, c.user, nil, []string{c.option})
+		}
+		for _, c := range grants {
+			updateRoles(s, &snap, c.user, []string{c.option}, nil)
+		}
+	}
+
+	if changed {
+		refresher.Refresh(s, id)
+		autoClosePoll(s, id)
+	}
+
+	for _, r := range invalid {
+		if err := s.MessageReactionRemove(r.src.ChannelID, r.src.MessageID, r.src.Emoji, r.user); err != nil {
+			fmt.Printf("failed to remove reaction from poll %d: %v\n", id, err)
+		}
+	}
+	if len(invalid) > 0 {
+		fmt.Printf("poll %d: removed %d reactions that couldn't be votes\n", id, len(invalid))
+	}
+
+	return nil
+}
+
+// contains reports whether s is in list
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameStrings reports whether a and b hold the same strings in the same order
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
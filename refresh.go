@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// editInterval is the least time between two edits of the same poll message
+const editInterval = 5 * time.Second
+
+// pollRefresher keeps poll messages showing the current votes. Refreshes
+// requested while an edit is pending are coalesced into that edit.
+type pollRefresher struct {
+	mu      sync.Mutex
+	pending map[int]bool
+	last    map[int]time.Time
+}
+
+var refresher = &pollRefresher{pending: make(map[int]bool), last: make(map[int]time.Time)}
+
+// Refresh schedules an edit of poll id's message, no sooner than editInterval
+// after the last one and no sooner than discord's rate limit on the channel
+// lets up
+func (r *pollRefresher) Refresh(s *discordgo.Session, id int) {
+	var channelID string
+	if !polls.View(id, func(e *pollEntry) { channelID = e.ChannelID }) {
+		return
+	}
+	limit := rateLimits.Wait(channelID, time.Now())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending[id] {
+		return
+	}
+	r.pending[id] = true
+
+	wait := editInterval - time.Since(r.last[id])
+	if limit > wait {
+		wait = limit
+	}
+	if wait < 0 {
+		wait = 0
+	}
+
+	time.AfterFunc(wait, func() { r.edit(s, id) })
+}
+
+// edit replaces poll id's message with its current state
+func (r *pollRefresher) edit(s *discordgo.Session, id int) {
+	r.mu.Lock()
+	delete(r.pending, id)
+	r.last[id] = time.Now()
+	r.mu.Unlock()
+
+	var msgs []pollMirror
+	var embeds []*discordgo.MessageEmbed
+	found := polls.View(id, func(e *pollEntry) {
+		msgs = e.messages()
+		for _, msg := range msgs {
+			embeds = append(embeds, pollEmbed(e, language(msg.GuildID, "")))
+		}
+	})
+	if !found || msgs[0].MessageID == "" {
+		r.Forget(id)
+		return
+	}
+
+	// every mirror shows the same combined votes
+	for i, msg := range msgs {
+		if _, err := s.ChannelMessageEditEmbed(msg.ChannelID, msg.MessageID, embeds[i]); err != nil {
+			fmt.Printf("failed to refresh poll %d: %v\n", id, err)
+		}
+	}
+}
+
+// Forget drops what the refresher knows about poll id once it no longer changes
+func (r *pollRefresher) Forget(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.pending[id] {
+		delete(r.last, id)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/throttle"
+)
+
+const (
+	// minReminderInterval stops a poll pinging people more often than this
+	minReminderInterval = 30 * time.Minute
+	// reminderCheckInterval is how often due reminders are looked for
+	reminderCheckInterval = time.Minute
+	// maxMessageLength is the longest message discord accepts
+	maxMessageLength = 2000
+)
+
+var (
+	// reminderUsers limits how often one person is reminded, across all polls
+	reminderUsers = throttle.NewLimiter(throttle.Rate{Burst: 1, Every: time.Hour})
+	// reminderSends paces reminder DMs so a big guild doesn't trip discord's
+	// spam protection
+	reminderSends = throttle.NewLimiter(throttle.Rate{Burst: 5, Every: 5 * time.Second})
+)
+
+// runReminders sends the reminders of open polls as they fall due
+func runReminders(s *discordgo.Session) {
+	for now := range time.Tick(reminderCheckInterval) {
+		for _, id := range dueReminders(now) {
+			if err := remind(s, id, now); err != nil {
+				fmt.Printf("failed to send reminders for poll %d: %v\n", id, err)
+			}
+		}
+	}
+}
+
+// dueReminders returns the IDs of the open polls whose reminder is due at now
+func dueReminders(now time.Time) []int {
+	ids := []int{}
+	polls.Each(func(e *pollEntry) {
+		if !e.Closed && e.RemindEvery > 0 && !now.Before(e.NextReminder) {
+			ids = append(ids, e.ID)
+		}
+	})
+
+	return ids
+}
+
+// remind pings the eligible members who haven't voted on poll id yet, by DM
+// or in the poll's channel as the guild prefers
+func remind(s *discordgo.Session, id int, now time.Time) error {
+	var e pollEntry
+	err := polls.Update(id, func(entry *pollEntry) error {
+		// move on to the next reminder first, so a failing one isn't retried
+		// every minute
+		entry.NextReminder = now.Add(entry.RemindEvery)
+		e = entry.snapshot()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	voters := e.Eligible
+	if voters == nil {
+		voters, err = eligibleVoters(s, e.GuildID, e.ChannelID, e.RoleID, e.Rules)
+		if err != nil {
+			return err
+		}
+	}
+
+	pending := []string{}
+	for _, user := range voters {
+		if e.Poll.HasVoted(user) || userConfigs.Get(user).NoReminders {
+			continue
+		}
+		if ok, _ := reminderUsers.Allow(user, now); ok {
+			pending = append(pending, user)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if guildConfigs.Get(e.GuildID).Reminders == "channel" {
+		return remindInChannel(s, &e, pending)
+	}
+
+	sent := 0
+	for _, user := range pending {
+		for {
+			ok, wait := reminderSends.Allow("", time.Now())
+			if ok {
+				break
+			}
+			time.Sleep(wait)
+		}
+
+		if err := sendDM(s, user, reminderEmbed(&e, language(e.GuildID, user))); err != nil {
+			continue
+		}
+		sent++
+	}
+
+	fmt.Printf("reminded %d of %d members about poll %d\n", sent, len(pending), id)
+	return nil
+}
+
+// reminderEmbed renders the DM reminding a member to vote on poll e
+func reminderEmbed(e *pollEntry, lang string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: e.Question,
+		URL:   fmt.Sprintf("https://discordapp.com/channels/%s/%s/%s", e.GuildID, e.ChannelID, e.MessageID),
+		Color: pollColor,
+	}
+	if e.Secret {
+		embed = ballotEmbed(e, lang)
+	}
+	if embed.Title == "" {
+		embed.Title = catalog.T(lang, "poll.untitled")
+	}
+
+	prefix := guildConfigs.Get(e.GuildID).Prefix
+	text := catalog.T(lang, "reminder.dm", e.ID, e.ChannelID) + "\n" +
+		catalog.T(lang, "reminder.opt_out", prefix+"reminders off")
+	if embed.Description != "" {
+		text += "\n\n" + embed.Description
+	}
+	embed.Description = text
+
+	return embed
+}
+
+// remindInChannel mentions users in the channel of poll e, over as many
+// messages as it takes
+func remindInChannel(s *discordgo.Session, e *pollEntry, users []string) error {
+	lang := language(e.GuildID, "")
+	prefix := guildConfigs.Get(e.GuildID).Prefix
+	header := catalog.T(lang, "reminder.channel", e.ID, prefix+"reminders off") + "\n"
+
+	mentions := []string{}
+	for _, user := range users {
+		mentions = append(mentions, "<@"+user+">")
+	}
+
+	for _, msg := range joinLimited(mentions, " ", maxMessageLength-len(header)) {
+		if _, err := s.ChannelMessageSend(e.ChannelID, header+msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// joinLimited joins items with sep into as few strings as it can without any
+// being longer than limit. Items too long on their own are cut up.
+func joinLimited(items []string, sep string, limit int) []string {
+	chunks := []string{}
+	current := ""
+	for _, item := range items {
+		for len(item) > limit {
+			if current != "" {
+				chunks = append(chunks, current)
+				current = ""
+			}
+			// cut between runes
+			i := limit
+			for i > 0 && !utf8.RuneStart(item[i]) {
+				i--
+			}
+			chunks = append(chunks, item[:i])
+			item = item[i:]
+		}
+		if current != "" && len(current)+len(sep)+len(item) > limit {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		if current != "" {
+			current += sep
+		}
+		current += item
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+func handleReminders(ctx *Context) error {
+	if len(ctx.Args) == 0 {
+		if userConfigs.Get(ctx.Message.Author.ID).NoReminders {
+			return ctx.Reply(ctx.T("reminders.off", ctx.Prefix+"reminders on"))
+		}
+		return ctx.Reply(ctx.T("reminders.on", ctx.Prefix+"reminders off"))
+	}
+
+	on, err := parseSwitch(strings.ToLower(ctx.Args[0]))
+	if err != nil {
+		return err
+	}
+
+	err = userConfigs.Update(ctx.Message.Author.ID, func(cfg *UserConfig) {
+		cfg.NoReminders = !on
+	})
+	if err != nil {
+		return err
+	}
+
+	if on {
+		return ctx.Reply(ctx.T("reminders.on", ctx.Prefix+"reminders off"))
+	}
+	return ctx.Reply(ctx.T("reminders.off", ctx.Prefix+"reminders on"))
+}
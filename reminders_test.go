@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinLimited(t *testing.T) {
+	tests := []struct {
+		items    []string
+		sep      string
+		limit    int
+		expected []string
+	}{
+		{[]string{}, ", ", 10, []string{}},
+		{[]string{"a", "b", "c"}, ", ", 10, []string{"a, b, c"}},
+		{[]string{"aaa", "bbb", "ccc"}, ", ", 8, []string{"aaa, bbb", "ccc"}},
+		{[]string{"aaa", "bbb", "ccc"}, ", ", 7, []string{"aaa", "bbb", "ccc"}},
+		{[]string{"aaaa", "bbbb"}, "\n", 9, []string{"aaaa\nbbbb"}},
+		{[]string{"a", "bbbbbbbbbbbb", "c"}, ", ", 5, []string{"a", "bbbbb", "bbbbb", "bb, c"}},
+		// multi-byte characters aren't cut in half
+		{[]string{"ééé"}, ", ", 3, []string{"é", "é", "é"}},
+	}
+
+	for _, test := range tests {
+		got := joinLimited(test.items, test.sep, test.limit)
+
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("joinLimited(%q, %q, %d) returned %q\nWant: %q", test.items, test.sep, test.limit, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+// roleOptionPattern is an option of a role poll, a role mention with an
+// optional cap on how many can pick it, like <@&123>:5
+var roleOptionPattern = regexp.MustCompile(`^<@&(\d+)>(?:\s*:\s*(\d+))?$`)
+
+var errRoleFull = errors.New("role is full")
+
+// roleFor returns the role given for option in a role poll, and how many
+// members may pick it, 0 if there's no cap
+func (e *pollEntry) roleFor(option string) (string, int) {
+	for i, o := range e.Poll.Options {
+		if o == option && i < len(e.Roles) {
+			return e.Roles[i], e.RoleCaps[i]
+		}
+	}
+
+	return "", 0
+}
+
+// roleVote gets a vote by voter for option on a role poll ready to be cast:
+// it refuses options that are full and, unless voters may pick several, takes
+// back voter's other votes, returning the options it took back
+func (e *pollEntry) roleVote(option, voter string) ([]string, error) {
+	choices := e.Poll.Choices(voter)
+	if contains(choices, option) {
+		return nil, poll.ErrAlreadyVoted
+	}
+	if _, cap := e.roleFor(option); cap > 0 && e.Poll.Count(option) >= cap {
+		return nil, errRoleFull
+	}
+	if e.Poll.MultiChoice {
+		return nil, nil
+	}
+
+	for _, o := range choices {
+		e.Poll.Unvote(o, voter)
+	}
+	return choices, nil
+}
+
+// roleOptions reads the options of a role poll, each a role mention with an
+// optional cap, returning the roles' names to use as the options along with
+// the roles and caps
+func roleOptions(s *discordgo.Session, guildID string, options []string) ([]string, []string, []int, error) {
+	names, roles, caps := []string{}, []string{}, []int{}
+	seen := make(map[string]bool)
+	for _, o := range options {
+		m := roleOptionPattern.FindStringSubmatch(o)
+		if m == nil {
+			return nil, nil, nil, newUserError("roles.invalid_option", o)
+		}
+
+		role, err := s.State.Role(guildID, m[1])
+		if err != nil {
+			return nil, nil, nil, newUserError("poll.invalid_role", o)
+		}
+		// the roles' names become the options, so two roles can't share one
+		if contains(roles, role.ID) {
+			return nil, nil, nil, newUserError("roles.duplicate_role", role.Name)
+		}
+		if seen[strings.ToLower(role.Name)] {
+			return nil, nil, nil, newUserError("roles.duplicate_name", role.Name)
+		}
+		seen[strings.ToLower(role.Name)] = true
+
+		cap := 0
+		if m[2] != "" {
+			cap, _ = strconv.Atoi(m[2])
+		}
+
+		names = append(names, role.Name)
+		roles = append(roles, role.ID)
+		caps = append(caps, cap)
+	}
+
+	return names, roles, caps, nil
+}
+
+// checkAssignable returns a userError unless the bot may give out roles in
+// guildID: it needs Manage Roles and a role above each of them
+func checkAssignable(s *discordgo.Session, guildID, channelID string, roles []string) error {
+	ok, err := hasPermissions(s, botID, channelID, discordgo.PermissionManageRoles)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newUserError("roles.no_permission")
+	}
+
+	g, err := s.State.Guild(guildID)
+	if err != nil {
+		return err
+	}
+	me, err := member(s, guildID, botID)
+	if err != nil {
+		return err
+	}
+
+	s.State.RLock()
+	defer s.State.RUnlock()
+
+	top := 0
+	positions := map[string]*discordgo.Role{}
+	for _, r := range g.Roles {
+		positions[r.ID] = r
+		if contains(me.Roles, r.ID) && r.Position > top {
+			top = r.Position
+		}
+	}
+
+	for _, id := range roles {
+		r, ok := positions[id]
+		if !ok || r.Managed || r.ID == guildID || r.Position >= top {
+			name := id
+			if ok {
+				name = r.Name
+			}
+			return newUserError("roles.too_high", name)
+		}
+	}
+
+	return nil
+}
+
+// updateRoles gives userID the roles of the options in grant and takes away
+// the ones of the options in revoke, for role poll e
+func updateRoles(s *discordgo.Session, e *pollEntry, userID string, grant, revoke []string) {
+	for _, o := range revoke {
+		role, _ := e.roleFor(o)
+		if err := s.GuildMemberRoleRemove(e.GuildID, userID, role); err != nil {
+			fmt.Printf("failed to take back role %s for poll %d: %v\n", role, e.ID, err)
+		}
+	}
+	for _, o := range grant {
+		role, _ := e.roleFor(o)
+		if err := s.GuildMemberRoleAdd(e.GuildID, userID, role); err != nil {
+			fmt.Printf("failed to give role %s for poll %d: %v\n", role, e.ID, err)
+		}
+	}
+}
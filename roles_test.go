@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+func TestRoleOptions(t *testing.T) {
+	state := discordgo.NewState()
+	state.GuildAdd(&discordgo.Guild{ID: "g", Roles: []*discordgo.Role{
+		{ID: "1", Name: "Red"},
+		{ID: "2", Name: "Blue"},
+		{ID: "3", Name: "red"},
+	}})
+	s := &discordgo.Session{State: state}
+
+	tests := []struct {
+		options []string
+		err     error
+		names   []string
+		roles   []string
+		caps    []int
+	}{
+		{[]string{"<@&1>", "<@&2>:5"}, nil, []string{"Red", "Blue"}, []string{"1", "2"}, []int{0, 5}},
+		{[]string{"<@&1>", "Blue"}, newUserError("roles.invalid_option", "Blue"), nil, nil, nil},
+		{[]string{"<@&1>", "<@&4>"}, newUserError("poll.invalid_role", "<@&4>"), nil, nil, nil},
+		{[]string{"<@&1>", "<@&2>", "<@&1>:3"}, newUserError("roles.duplicate_role", "Red"), nil, nil, nil},
+		// names are compared without case, like the options of any poll
+		{[]string{"<@&1>", "<@&3>"}, newUserError("roles.duplicate_name", "red"), nil, nil, nil},
+	}
+
+	for i, test := range tests {
+		names, roles, caps, err := roleOptions(s, "g", test.options)
+
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("test %d: roleOptions returned incorrect error.\nGot: %v\nWant: %v", i, err, test.err)
+		}
+		if !reflect.DeepEqual(names, test.names) || !reflect.DeepEqual(roles, test.roles) || !reflect.DeepEqual(caps, test.caps) {
+			t.Errorf("test %d: roleOptions returned (%q, %q, %v), want (%q, %q, %v)",
+				i, names, roles, caps, test.names, test.roles, test.caps)
+		}
+	}
+}
+
+func TestRoleVote(t *testing.T) {
+	// Red takes at most 2 members and Green 1, Blue has no cap
+	votes := [][2]string{{"Red", "ana"}, {"Red", "ben"}, {"Blue", "cat"}, {"Green", "dan"}, {"Blue", "dan"}}
+
+	tests := []struct {
+		multi    bool
+		option   string
+		voter    string
+		err      error
+		taken    []string
+		expected []string
+	}{
+		{false, "Blue", "eve", nil, []string{}, []string{}},
+		{false, "Red", "eve", errRoleFull, nil, []string{}},
+		{false, "Green", "eve", errRoleFull, nil, []string{}},
+		{false, "Red", "ana", poll.ErrAlreadyVoted, nil, []string{"Red"}},
+		// a swap takes back the voter's vote and returns it
+		{false, "Blue", "ana", nil, []string{"Red"}, []string{}},
+		{false, "Red", "cat", errRoleFull, nil, []string{"Blue"}},
+		// a full role isn't swapped to, so the old vote stays
+		{false, "Green", "cat", errRoleFull, nil, []string{"Blue"}},
+		// a voter who may pick several keeps their other votes
+		{true, "Red", "cat", errRoleFull, nil, []string{"Blue"}},
+		{true, "Blue", "ana", nil, nil, []string{"Red"}},
+		{true, "Blue", "dan", poll.ErrAlreadyVoted, nil, []string{"Blue", "Green"}},
+	}
+
+	for i, test := range tests {
+		p, err := poll.NewPoll([]string{"Red", "Blue", "Green"})
+		if err != nil {
+			t.Fatalf("NewPoll returned unexpected error: %v", err)
+		}
+		p.MultiChoice = true
+		for _, v := range votes {
+			if err := p.Vote(v[0], v[1]); err != nil {
+				t.Fatalf("Vote returned unexpected error: %v", err)
+			}
+		}
+		p.MultiChoice = test.multi
+		e := &pollEntry{Roles: []string{"1", "2", "3"}, RoleCaps: []int{2, 0, 1}, Poll: p}
+
+		taken, err := e.roleVote(test.option, test.voter)
+
+		if err != test.err {
+			t.Errorf("test %d: roleVote returned incorrect error.\nGot: %v\nWant: %v", i, err, test.err)
+		}
+		if !reflect.DeepEqual(taken, test.taken) {
+			t.Errorf("test %d: roleVote took back %q, want %q", i, taken, test.taken)
+		}
+		if choices := p.Choices(test.voter); !reflect.DeepEqual(choices, test.expected) {
+			t.Errorf("test %d: roleVote left %s with %q, want %q", i, test.voter, choices, test.expected)
+		}
+	}
+}
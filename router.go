@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// HandlerFunc runs a single command invocation
+type HandlerFunc func(*Context) error
+
+// Middleware wraps a HandlerFunc with extra behaviour such as logging or recovery
+type Middleware func(HandlerFunc) HandlerFunc
+
+// ArgSpec describes how the text after a command name is split into arguments
+type ArgSpec struct {
+	// Separator splits the arguments, an empty Separator splits on whitespace
+	Separator string
+	Min       int
+	// Max is the maximum number of arguments, 0 means there is no limit
+	Max int
+}
+
+// Command is a bot command registered with a Router
+type Command struct {
+	Name        string
+	Aliases     []string
+	Usage       string
+	Description string
+	Examples    []string
+	Args        ArgSpec
+	// Permissions is the set of discordgo permission bits the caller needs
+	Permissions int
+	// Cooldown overrides defaultCooldown for this command
+	Cooldown *Cooldown
+	Handler  HandlerFunc
+}
+
+// Context holds everything a command handler needs to know about an invocation
+type Context struct {
+	Router  *Router
+	Session *discordgo.Session
+	Message *discordgo.MessageCreate
+	Command *Command
+	// Prefix is the preferred command prefix where the command was invoked
+	Prefix string
+	// Name is the command name or alias used to invoke the command
+	Name string
+	// Raw is the unsplit text following the command name
+	Raw  string
+	Args []string
+}
+
+// Reply sends msg to the channel the command was invoked in
+func (ctx *Context) Reply(msg string) error {
+	_, err := ctx.Session.ChannelMessageSend(ctx.Message.ChannelID, msg)
+	return err
+}
+
+// UsageError is returned when a command is invoked with the wrong arguments
+type UsageError struct {
+	Command *Command
+}
+
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("usage: %s %s", e.Command.Name, e.Command.Usage)
+}
+
+// PermissionError is returned when the caller lacks the permissions a command requires
+type PermissionError struct {
+	Command *Command
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("missing permissions for %s", e.Command.Name)
+}
+
+// PrefixFunc returns the prefixes a command in m may start with, the first
+// being the preferred one
+type PrefixFunc func(s *discordgo.Session, m *discordgo.MessageCreate) []string
+
+// userError is an error caused by the user, explained to them by the catalog
+// message key
+type userError struct {
+	key  string
+	args []interface{}
+}
+
+func newUserError(key string, args ...interface{}) *userError {
+	return &userError{key, args}
+}
+
+func (e *userError) Error() string {
+	return catalog.T(defaultLanguage, e.key, e.args...)
+}
+
+// Router dispatches messages to registered commands by prefix and name
+type Router struct {
+	Prefixes PrefixFunc
+
+	commands   map[string]*Command
+	ordered    []*Command
+	middleware []Middleware
+}
+
+// NewRouter creates a Router that recognises commands starting with one of
+// the prefixes returned by prefixes
+func NewRouter(prefixes PrefixFunc) *Router {
+	return &Router{
+		Prefixes: prefixes,
+		commands: make(map[string]*Command),
+	}
+}
+
+// Register adds a command to the router under its name and aliases
+func (r *Router) Register(c *Command) error {
+	if c.Handler == nil {
+		return fmt.Errorf("command %q has no handler", c.Name)
+	}
+
+	names := append([]string{c.Name}, c.Aliases...)
+	for _, name := range names {
+		if _, ok := r.commands[name]; ok {
+			return fmt.Errorf("command %q is already registered", name)
+		}
+	}
+
+	for _, name := range names {
+		r.commands[name] = c
+	}
+	r.ordered = append(r.ordered, c)
+
+	return nil
+}
+
+// Use appends middleware that wraps every command handler, the first added is outermost
+func (r *Router) Use(m ...Middleware) {
+	r.middleware = append(r.middleware, m...)
+}
+
+// Commands returns the registered commands in registration order
+func (r *Router) Commands() []*Command {
+	return r.ordered
+}
+
+// Lookup returns the command registered under name, if any
+func (r *Router) Lookup(name string) (*Command, bool) {
+	c, ok := r.commands[strings.ToLower(name)]
+	return c, ok
+}
+
+// Dispatch runs the command in m, if m contains one. It reports whether a
+// command was found.
+func (r *Router) Dispatch(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	prefixes := r.Prefixes(s, m)
+	content, ok := trimPrefix(m.Content, prefixes)
+	if !ok {
+		return false
+	}
+
+	name := content
+	raw := ""
+	if i := strings.IndexAny(content, " \n"); i >= 0 {
+		name, raw = content[:i], strings.TrimSpace(content[i+1:])
+	}
+
+	c, ok := r.Lookup(name)
+	if !ok {
+		return false
+	}
+
+	ctx := &Context{
+		Router:  r,
+		Session: s,
+		Message: m,
+		Command: c,
+		Prefix:  prefixes[0],
+		Name:    name,
+		Raw:     raw,
+	}
+
+	h := r.handler(c)
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+
+	if err := h(ctx); err != nil {
+		fmt.Printf("command %s failed: %v\n", c.Name, err)
+		replyError(ctx, err)
+	}
+
+	return true
+}
+
+// trimPrefix strips the first of prefixes that content starts with, and any
+// space following it
+func trimPrefix(content string, prefixes []string) (string, bool) {
+	for _, p := range prefixes {
+		if p != "" && strings.HasPrefix(content, p) {
+			return strings.TrimLeft(content[len(p):], " "), true
+		}
+	}
+
+	return content, false
+}
+
+// handler wraps a command's handler with its argument and permission checks
+func (r *Router) handler(c *Command) HandlerFunc {
+	return func(ctx *Context) error {
+		if c.Permissions != 0 {
+			ok, err := hasPermissions(ctx.Session, ctx.Message.Author.ID, ctx.Message.ChannelID, c.Permissions)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return &PermissionError{c}
+			}
+		}
+
+		ctx.Args = splitArgs(ctx.Raw, c.Args.Separator)
+		if len(ctx.Args) < c.Args.Min || (c.Args.Max > 0 && len(ctx.Args) > c.Args.Max) {
+			return &UsageError{c}
+		}
+
+		return c.Handler(ctx)
+	}
+}
+
+// splitArgs splits raw on sep, dropping empty arguments
+func splitArgs(raw, sep string) []string {
+	if sep == "" {
+		return strings.Fields(raw)
+	}
+
+	args := []string{}
+	for _, a := range strings.Split(raw, sep) {
+		if a = strings.TrimSpace(a); a != "" {
+			args = append(args, a)
+		}
+	}
+
+	return args
+}
+
+// hasPermissions reports whether userID holds all of perms in channelID
+func hasPermissions(s *discordgo.Session, userID, channelID string, perms int) (bool, error) {
+	p, err := s.State.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		p, err = s.UserChannelPermissions(userID, channelID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if p&discordgo.PermissionAdministrator != 0 {
+		return true, nil
+	}
+
+	return p&perms == perms, nil
+}
+
+// replyError tells the caller why their command didn't work
+func replyError(ctx *Context, err error) {
+	var msg string
+	switch e := err.(type) {
+	case *UsageError:
+		msg = ctx.T("usage", commandSyntax(ctx.Lang(), ctx.Prefix, e.Command))
+	case *PermissionError:
+		msg = ctx.T("no_permission")
+	case *userError:
+		msg = ctx.T(e.key, e.args...)
+	default:
+		if key, ok := errorKeys[err]; ok {
+			msg = ctx.T(key)
+		} else {
+			msg = ctx.T("command_failed")
+		}
+	}
+
+	if err := ctx.Reply(msg); err != nil {
+		fmt.Printf("failed to reply to %s: %v\n", ctx.Message.ID, err)
+	}
+}
+
+// logCommands is middleware that logs each command invocation
+func logCommands(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) error {
+		start := time.Now()
+		err := next(ctx)
+		fmt.Printf("%s ran %s in %v\n", ctx.Message.Author.ID, ctx.Command.Name, time.Since(start))
+		return err
+	}
+}
+
+// recoverCommands is middleware that turns a panicking handler into an error
+func recoverCommands(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Printf("command %s panicked: %v\n%s", ctx.Command.Name, r, debug.Stack())
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		return next(ctx)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func noop(ctx *Context) error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRouter(nil)
+
+	tests := []struct {
+		command  *Command
+		expected error
+	}{
+		{&Command{Name: "poll", Aliases: []string{"p"}, Handler: noop}, nil},
+		{&Command{Name: "close", Handler: noop}, nil},
+		{&Command{Name: "vote"}, errors.New(`command "vote" has no handler`)},
+		{&Command{Name: "poll", Handler: noop}, errors.New(`command "poll" is already registered`)},
+		{&Command{Name: "pick", Aliases: []string{"p"}, Handler: noop}, errors.New(`command "p" is already registered`)},
+		// a command that failed to register doesn't keep its name
+		{&Command{Name: "pick", Handler: noop}, nil},
+	}
+
+	for i, test := range tests {
+		err := r.Register(test.command)
+
+		if !reflect.DeepEqual(err, test.expected) {
+			t.Errorf("test %d: Register returned incorrect error.\nGot: %v\nWant: %v", i, err, test.expected)
+		}
+	}
+
+	names := []string{}
+	for _, c := range r.Commands() {
+		names = append(names, c.Name)
+	}
+	if expected := []string{"poll", "close", "pick"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Commands returned %q, want %q", names, expected)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var got *Context
+	record := func(ctx *Context) error {
+		got = ctx
+		return nil
+	}
+
+	r := NewRouter(func(s *discordgo.Session, m *discordgo.MessageCreate) []string {
+		return []string{"!", "<@123>", "<@!123>"}
+	})
+	r.Register(&Command{Name: "poll", Aliases: []string{"p"}, Args: ArgSpec{Separator: ","}, Handler: record})
+	r.Register(&Command{Name: "close", Handler: record})
+
+	tests := []struct {
+		content string
+		found   bool
+		name    string
+		raw     string
+		args    []string
+	}{
+		{"!poll pizza, tacos", true, "poll", "pizza, tacos", []string{"pizza", "tacos"}},
+		{"!p  pizza,tacos ", true, "p", "pizza,tacos", []string{"pizza", "tacos"}},
+		{"!POLL pizza", true, "POLL", "pizza", []string{"pizza"}},
+		{"!close\n3", true, "close", "3", []string{"3"}},
+		{"!close", true, "close", "", []string{}},
+		{"<@123> close 3", true, "close", "3", []string{"3"}},
+		{"<@!123>close 3", true, "close", "3", []string{"3"}},
+		{"!nothing 3", false, "", "", nil},
+		{"close 3", false, "", "", nil},
+		{"<@456> close 3", false, "", "", nil},
+	}
+
+	for i, test := range tests {
+		got = nil
+		found := r.Dispatch(nil, &discordgo.MessageCreate{Message: &discordgo.Message{Content: test.content}})
+
+		if found != test.found {
+			t.Errorf("test %d: Dispatch(%q) returned %v, want %v", i, test.content, found, test.found)
+			continue
+		}
+		if !test.found {
+			if got != nil {
+				t.Errorf("test %d: Dispatch(%q) ran %s", i, test.content, got.Command.Name)
+			}
+			continue
+		}
+		if got == nil {
+			t.Errorf("test %d: Dispatch(%q) didn't run a command", i, test.content)
+			continue
+		}
+		if got.Name != test.name || got.Raw != test.raw || !reflect.DeepEqual(got.Args, test.args) {
+			t.Errorf("test %d: Dispatch(%q) ran %q with (%q, %q), want %q with (%q, %q)",
+				i, test.content, got.Name, got.Raw, got.Args, test.name, test.raw, test.args)
+		}
+		if got.Prefix != "!" {
+			t.Errorf("test %d: Dispatch(%q) gave prefix %q, want the preferred one", i, test.content, got.Prefix)
+		}
+	}
+}
+
+func TestTrimPrefix(t *testing.T) {
+	prefixes := []string{"", "!", "<@123>", "<@!123>"}
+
+	tests := []struct {
+		content  string
+		expected string
+		ok       bool
+	}{
+		{"!poll", "poll", true},
+		{"! poll", "poll", true},
+		{"<@123> poll", "poll", true},
+		{"<@123>   poll", "poll", true},
+		{"<@!123>poll", "poll", true},
+		{"<@456> poll", "<@456> poll", false},
+		// an empty prefix never matches
+		{"poll", "poll", false},
+	}
+
+	for i, test := range tests {
+		content, ok := trimPrefix(test.content, prefixes)
+
+		if content != test.expected || ok != test.ok {
+			t.Errorf("test %d: trimPrefix(%q) returned (%q, %v), want (%q, %v)",
+				i, test.content, content, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		raw      string
+		sep      string
+		expected []string
+	}{
+		{"pizza tacos  sushi", "", []string{"pizza", "tacos", "sushi"}},
+		{"pizza, tacos ,sushi", ",", []string{"pizza", "tacos", "sushi"}},
+		{" pizza , , tacos, ", ",", []string{"pizza", "tacos"}},
+		{"pizza place, tacos", ",", []string{"pizza place", "tacos"}},
+		{"", ",", []string{}},
+		{"   ", "", []string{}},
+	}
+
+	for i, test := range tests {
+		args := splitArgs(test.raw, test.sep)
+
+		if !reflect.DeepEqual(args, test.expected) {
+			t.Errorf("test %d: splitArgs(%q, %q) returned %q, want %q", i, test.raw, test.sep, args, test.expected)
+		}
+	}
+}
+
+func TestHandlerArgs(t *testing.T) {
+	r := NewRouter(nil)
+
+	tests := []struct {
+		args     ArgSpec
+		raw      string
+		expected bool
+	}{
+		{ArgSpec{}, "", true},
+		{ArgSpec{Min: 1}, "", false},
+		{ArgSpec{Min: 1}, "3", true},
+		{ArgSpec{Min: 1, Max: 2}, "3 4", true},
+		{ArgSpec{Min: 1, Max: 2}, "3 4 5", false},
+		{ArgSpec{Separator: ",", Min: 2}, "pizza place, tacos", true},
+		{ArgSpec{Separator: ",", Min: 2}, "pizza place tacos", false},
+		// Max 0 means there is no limit
+		{ArgSpec{Max: 0}, "1 2 3 4 5 6 7 8 9 10", true},
+	}
+
+	for i, test := range tests {
+		ran := false
+		c := &Command{Name: "poll", Args: test.args, Handler: func(ctx *Context) error {
+			ran = true
+			return nil
+		}}
+
+		err := r.handler(c)(&Context{Command: c, Raw: test.raw})
+
+		var expected error
+		if !test.expected {
+			expected = &UsageError{c}
+		}
+		if !reflect.DeepEqual(err, expected) {
+			t.Errorf("test %d: handler returned incorrect error.\nGot: %v\nWant: %v", i, err, expected)
+		}
+		if ran != test.expected {
+			t.Errorf("test %d: handler ran the command: %v, want %v", i, ran, test.expected)
+		}
+	}
+}
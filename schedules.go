@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/mroseman95/discord-poll-bot/cron"
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+const (
+	// scheduleCheckInterval is how often due schedules are looked for, cron's
+	// finest resolution
+	scheduleCheckInterval = time.Minute
+	// missedRunGrace is how late a scheduled poll may still be posted, runs
+	// missed for longer while the bot was offline are skipped
+	missedRunGrace = time.Hour
+	// scheduleTimeLayout is how the next run of a schedule is shown
+	scheduleTimeLayout = "Mon 2 Jan 15:04 MST"
+)
+
+var closePreviousPattern = regexp.MustCompile(`(?:^|\s)--close-previous\b`)
+
+// pollSchedule posts a fresh poll from the same definition on a cron schedule
+type pollSchedule struct {
+	ID        int    `json:"id"`
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	CreatorID string `json:"creator_id"`
+	Cron      string `json:"cron"`
+	// Poll is the poll's definition, as it would follow !poll
+	Poll string `json:"poll"`
+	// ClosePrevious closes the last poll posted when the next one is
+	ClosePrevious bool      `json:"close_previous,omitempty"`
+	Paused        bool      `json:"paused,omitempty"`
+	NextRun       time.Time `json:"next_run"`
+	LastPollID    int       `json:"last_poll_id,omitempty"`
+}
+
+// next returns when sc runs after t, in its guild's time zone
+func (sc *pollSchedule) next(t time.Time) time.Time {
+	c, err := cron.Parse(sc.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return c.Next(t.In(guildLocation(sc.GuildID)))
+}
+
+// scheduleStore caches poll schedules in memory and persists them to disk
+type scheduleStore struct {
+	mu        sync.Mutex
+	file      string
+	NextID    int                   `json:"next_id"`
+	Schedules map[int]*pollSchedule `json:"schedules"`
+}
+
+var schedules = &scheduleStore{file: "schedules.json", NextID: 1, Schedules: make(map[int]*pollSchedule)}
+
+// load reads the persisted schedules
+func (ss *scheduleStore) load() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	return loadJSON(ss.file, ss)
+}
+
+// Add stores sc under a new ID, which it returns
+func (ss *scheduleStore) Add(sc *pollSchedule) (int, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	sc.ID = ss.NextID
+	ss.NextID++
+	ss.Schedules[sc.ID] = sc
+
+	return sc.ID, saveJSON(ss.file, ss)
+}
+
+// Remove forgets the schedule id
+func (ss *scheduleStore) Remove(id int) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	delete(ss.Schedules, id)
+
+	return saveJSON(ss.file, ss)
+}
+
+// Update applies f to the schedule id and persists the result if f succeeds
+func (ss *scheduleStore) Update(id int, f func(*pollSchedule) error) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	sc, ok := ss.Schedules[id]
+	if !ok {
+		return newUserError("schedule.not_found", id)
+	}
+	if err := f(sc); err != nil {
+		return err
+	}
+
+	return saveJSON(ss.file, ss)
+}
+
+// Each calls f with every schedule, without changing them
+func (ss *scheduleStore) Each(f func(*pollSchedule)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	for _, sc := range ss.Schedules {
+		f(sc)
+	}
+}
+
+// runSchedules posts scheduled polls as they fall due
+func runSchedules(s *discordgo.Session) {
+	for now := range time.Tick(scheduleCheckInterval) {
+		due := []int{}
+		schedules.Each(func(sc *pollSchedule) {
+			if !sc.Paused && !sc.NextRun.IsZero() && !now.Before(sc.NextRun) {
+				due = append(due, sc.ID)
+			}
+		})
+
+		for _, id := range due {
+			if err := runSchedule(s, id, now); err != nil {
+				fmt.Printf("failed to run schedule %d: %v\n", id, err)
+			}
+		}
+	}
+}
+
+// runSchedule posts the poll of schedule id, closing the previous one if it
+// should, and works out when it runs next
+func runSchedule(s *discordgo.Session, id int, now time.Time) error {
+	var sc pollSchedule
+	err := schedules.Update(id, func(entry *pollSchedule) error {
+		sc = *entry
+		entry.NextRun = entry.next(now)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if now.Sub(sc.NextRun) > missedRunGrace {
+		fmt.Printf("skipped schedule %d, missed at %v\n", id, sc.NextRun)
+		return nil
+	}
+
+	if sc.ClosePrevious && sc.LastPollID != 0 {
+		err := closePoll(s, sc.LastPollID)
+		if _, closed := err.(*userError); err != nil && !closed {
+			fmt.Printf("failed to close poll %d for schedule %d: %v\n", sc.LastPollID, id, err)
+		}
+	}
+
+	spec, err := parsePollSpec(sc.Poll)
+	if err != nil {
+		return err
+	}
+	e, err := newPollEntry(s, sc.GuildID, sc.ChannelID, sc.CreatorID, spec)
+	if err != nil {
+		return err
+	}
+	if err := openPoll(s, e); err != nil {
+		return err
+	}
+
+	return schedules.Update(id, func(entry *pollSchedule) error {
+		entry.LastPollID = e.ID
+		return nil
+	})
+}
+
+// scheduleSubcommands are the things !poll schedule does besides creating one
+var scheduleSubcommands = map[string]HandlerFunc{
+	"list":   handleScheduleList,
+	"pause":  handleSchedulePause,
+	"resume": handleScheduleResume,
+	"delete": handleScheduleDelete,
+}
+
+func handlePollSchedule(ctx *Context) error {
+	if ctx.GuildID() == "" {
+		return newUserError("schedule.guild_only")
+	}
+
+	if len(ctx.Args) > 0 {
+		if sub, ok := scheduleSubcommands[strings.ToLower(ctx.Args[0])]; ok {
+			ctx.Args = ctx.Args[1:]
+			return sub(ctx)
+		}
+	}
+
+	m := questionPattern.FindStringSubmatch(ctx.Raw)
+	if m == nil {
+		return &UsageError{ctx.Command}
+	}
+	expr := strings.TrimSpace(m[1])
+	if _, err := cron.Parse(expr); err != nil {
+		return newUserError("schedule.invalid_cron", expr)
+	}
+
+	ok, err := hasPermissions(ctx.Session, ctx.Message.Author.ID, ctx.Message.ChannelID, discordgo.PermissionManageMessages)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &PermissionError{ctx.Command}
+	}
+
+	definition := ctx.Raw[len(m[0]):]
+	closePrevious := closePreviousPattern.MatchString(definition)
+	definition = strings.TrimSpace(closePreviousPattern.ReplaceAllString(definition, ""))
+
+	// check the definition now rather than when it first runs
+	spec, err := parsePollSpec(definition)
+	if err != nil {
+		return err
+	}
+	if len(spec.Options) < 2 {
+		return poll.ErrTooFewOptions
+	}
+
+	sc := &pollSchedule{
+		GuildID:       ctx.GuildID(),
+		ChannelID:     ctx.Message.ChannelID,
+		CreatorID:     ctx.Message.Author.ID,
+		Cron:          expr,
+		Poll:          definition,
+		ClosePrevious: closePrevious,
+	}
+	sc.NextRun = sc.next(time.Now())
+	if sc.NextRun.IsZero() {
+		return newUserError("schedule.invalid_cron", expr)
+	}
+
+	id, err := schedules.Add(sc)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created schedule %d in %s\n", id, sc.ChannelID)
+	return ctx.Reply(ctx.T("schedule.created", id, sc.NextRun.Format(scheduleTimeLayout)))
+}
+
+func handleScheduleList(ctx *Context) error {
+	list := []pollSchedule{}
+	schedules.Each(func(sc *pollSchedule) {
+		if sc.GuildID == ctx.GuildID() {
+			list = append(list, *sc)
+		}
+	})
+	if len(list) == 0 {
+		return ctx.Reply(ctx.T("schedule.none"))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	lines := []string{}
+	for _, sc := range list {
+		title := sc.Poll
+		if spec, err := parsePollSpec(sc.Poll); err == nil && spec.Question != "" {
+			title = spec.Question
+		}
+
+		status := ctx.T("schedule.next", sc.NextRun.In(guildLocation(sc.GuildID)).Format(scheduleTimeLayout))
+		if sc.Paused {
+			status = ctx.T("schedule.paused")
+		}
+
+		lines = append(lines, fmt.Sprintf("**#%d** `%s` <#%s> %s · %s", sc.ID, sc.Cron, sc.ChannelID, title, status))
+	}
+
+	for _, msg := range joinLimited(lines, "\n", maxMessageLength) {
+		if err := ctx.Reply(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateSchedule applies f to the schedule named by the first argument, if the
+// invoking user may manage it
+func updateSchedule(ctx *Context, f func(*pollSchedule)) (int, error) {
+	if len(ctx.Args) == 0 {
+		return 0, &UsageError{ctx.Command}
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(ctx.Args[0], "#"))
+	if err != nil {
+		return 0, newUserError("schedule.not_found", ctx.Args[0])
+	}
+
+	return id, schedules.Update(id, func(sc *pollSchedule) error {
+		if sc.GuildID != ctx.GuildID() {
+			return newUserError("schedule.not_found", id)
+		}
+		if sc.CreatorID != ctx.Message.Author.ID {
+			ok, err := hasPermissions(ctx.Session, ctx.Message.Author.ID, sc.ChannelID, discordgo.PermissionManageMessages)
+			if err != nil || !ok {
+				return &PermissionError{ctx.Command}
+			}
+		}
+		f(sc)
+		return nil
+	})
+}
+
+func handleSchedulePause(ctx *Context) error {
+	id, err := updateSchedule(ctx, func(sc *pollSchedule) {
+		sc.Paused = true
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.Reply(ctx.T("schedule.paused_id", id))
+}
+
+func handleScheduleResume(ctx *Context) error {
+	var next time.Time
+	id, err := updateSchedule(ctx, func(sc *pollSchedule) {
+		sc.Paused = false
+		sc.NextRun = sc.next(time.Now())
+		next = sc.NextRun
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.Reply(ctx.T("schedule.resumed", id, next.Format(scheduleTimeLayout)))
+}
+
+func handleScheduleDelete(ctx *Context) error {
+	id, err := updateSchedule(ctx, func(sc *pollSchedule) {})
+	if err != nil {
+		return err
+	}
+	if err := schedules.Remove(id); err != nil {
+		return err
+	}
+
+	return ctx.Reply(ctx.T("schedule.deleted", id))
+}
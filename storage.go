@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// dataDir returns the directory the bot persists its state in
+func dataDir() string {
+	if dir := os.Getenv("DISCORD_BOT_DATA_DIR"); dir != "" {
+		return dir
+	}
+
+	return "data"
+}
+
+// loadJSON reads the JSON file name in the data directory into v. A missing
+// file leaves v untouched.
+func loadJSON(name string, v interface{}) error {
+	b, err := ioutil.ReadFile(filepath.Join(dataDir(), name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// saveJSON writes v to the JSON file name in the data directory, replacing it
+// atomically so a crash never leaves a half written file behind
+func saveJSON(name string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := dataDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// loadSecret returns the secret in the environment variable env or else in the
+// file name in the data directory, which it creates with a random secret the
+// first time, readable only by the bot
+func loadSecret(env, name string) ([]byte, error) {
+	if secret := os.Getenv(env); secret != "" {
+		return []byte(secret), nil
+	}
+
+	dir := dataDir()
+	secret, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if !os.IsNotExist(err) {
+		return secret, err
+	}
+
+	secret = make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return secret, ioutil.WriteFile(filepath.Join(dir, name), secret, 0600)
+}
+
+// guildStore keeps each guild's items by name, persisted to a JSON file in
+// the data directory. Items are held as JSON, the stores for each kind of
+// item embed it and convert them.
+type guildStore struct {
+	mu    sync.Mutex
+	file  string
+	items map[string]map[string]json.RawMessage
+}
+
+func newGuildStore(file string) guildStore {
+	return guildStore{file: file, items: make(map[string]map[string]json.RawMessage)}
+}
+
+// load reads the persisted items
+func (gs *guildStore) load() error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	return loadJSON(gs.file, &gs.items)
+}
+
+// get reads guildID's item name into v, reporting whether it has one
+func (gs *guildStore) get(guildID, name string, v interface{}) bool {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	item, ok := gs.items[guildID][name]
+	return ok && json.Unmarshal(item, v) == nil
+}
+
+// set saves v as guildID's item name, replacing any it had
+func (gs *guildStore) set(guildID, name string, v interface{}) error {
+	item, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.items[guildID] == nil {
+		gs.items[guildID] = make(map[string]json.RawMessage)
+	}
+	gs.items[guildID][name] = item
+
+	return saveJSON(gs.file, gs.items)
+}
+
+// Delete forgets guildID's item name, reporting whether it had one
+func (gs *guildStore) Delete(guildID, name string) (bool, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if _, ok := gs.items[guildID][name]; !ok {
+		return false, nil
+	}
+	delete(gs.items[guildID], name)
+
+	return true, saveJSON(gs.file, gs.items)
+}
+
+// Names returns the names of guildID's items in order
+func (gs *guildStore) Names(guildID string) []string {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	names := []string{}
+	for name := range gs.items[guildID] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
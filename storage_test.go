@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGuildStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discord-poll-bot")
+	if err != nil {
+		t.Fatalf("TempDir returned unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("DISCORD_BOT_DATA_DIR", dir)
+	defer os.Unsetenv("DISCORD_BOT_DATA_DIR")
+
+	ws := &webhookStore{newGuildStore("hooks.json")}
+	hooks := []struct {
+		guildID, name string
+		h             resultHook
+	}{
+		{"g1", "results", resultHook{ID: "1", Format: "text"}},
+		{"g1", "archive", resultHook{ID: "2", Format: "embed"}},
+		{"g2", "results", resultHook{ID: "3", Format: "text"}},
+	}
+	for _, hook := range hooks {
+		if err := ws.Set(hook.guildID, hook.name, hook.h); err != nil {
+			t.Fatalf("Set returned unexpected error: %v", err)
+		}
+	}
+
+	if names := ws.Names("g1"); !reflect.DeepEqual(names, []string{"archive", "results"}) {
+		t.Errorf("Names returned %q, want them in order", names)
+	}
+	if h, ok := ws.Get("g2", "results"); !ok || h != hooks[2].h {
+		t.Errorf("Get returned (%+v, %v), want the hook of g2", h, ok)
+	}
+	if _, ok := ws.Get("g2", "archive"); ok {
+		t.Errorf("Get found a hook of another guild")
+	}
+
+	if ok, err := ws.Delete("g1", "results"); !ok || err != nil {
+		t.Errorf("Delete returned (%v, %v), want (true, nil)", ok, err)
+	}
+	if ok, err := ws.Delete("g1", "results"); ok || err != nil {
+		t.Errorf("Delete of a deleted hook returned (%v, %v), want (false, nil)", ok, err)
+	}
+
+	// what's left is read back from disk
+	loaded := &webhookStore{newGuildStore("hooks.json")}
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load returned unexpected error: %v", err)
+	}
+	if names := loaded.Names("g1"); !reflect.DeepEqual(names, []string{"archive"}) {
+		t.Errorf("load read names %q, want only archive", names)
+	}
+	if h, ok := loaded.Get("g1", "archive"); !ok || h != hooks[1].h {
+		t.Errorf("load read (%+v, %v), want %+v", h, ok, hooks[1].h)
+	}
+}
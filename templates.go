@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// templateStore keeps each guild's poll templates, definitions as they would
+// follow !poll, by name
+type templateStore struct {
+	guildStore
+}
+
+var templates = &templateStore{newGuildStore("templates.json")}
+
+// Get returns the definition of guildID's template name
+func (t *templateStore) Get(guildID, name string) (string, bool) {
+	var definition string
+	ok := t.get(guildID, name, &definition)
+	return definition, ok
+}
+
+// Set saves definition as guildID's template name, replacing any it had
+func (t *templateStore) Set(guildID, name, definition string) error {
+	return t.set(guildID, name, definition)
+}
+
+var templateSubcommands = map[string]HandlerFunc{
+	"save":   handleTemplateSave,
+	"use":    handleTemplateUse,
+	"list":   handleTemplateList,
+	"delete": handleTemplateDelete,
+}
+
+func handleTemplate(ctx *Context) error {
+	if ctx.GuildID() == "" {
+		return newUserError("template.guild_only")
+	}
+
+	sub, ok := templateSubcommands[strings.ToLower(ctx.Args[0])]
+	if !ok {
+		return &UsageError{ctx.Command}
+	}
+
+	ctx.Raw = strings.TrimSpace(strings.TrimPrefix(ctx.Raw, ctx.Args[0]))
+	ctx.Args = ctx.Args[1:]
+	return sub(ctx)
+}
+
+// templateName returns the template named by the first argument, and the rest
+// of the command after it
+func templateName(ctx *Context) (string, string, error) {
+	if len(ctx.Args) == 0 {
+		return "", "", &UsageError{ctx.Command}
+	}
+
+	name := strings.ToLower(ctx.Args[0])
+	if !templateNamePattern.MatchString(name) {
+		return "", "", newUserError("template.invalid_name", ctx.Args[0])
+	}
+
+	return name, strings.TrimSpace(strings.TrimPrefix(ctx.Raw, ctx.Args[0])), nil
+}
+
+// canManageTemplates reports whether the invoking user may change templates
+func canManageTemplates(ctx *Context) error {
+	ok, err := hasPermissions(ctx.Session, ctx.Message.Author.ID, ctx.Message.ChannelID, discordgo.PermissionManageMessages)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &PermissionError{ctx.Command}
+	}
+
+	return nil
+}
+
+func handleTemplateSave(ctx *Context) error {
+	name, definition, err := templateName(ctx)
+	if err != nil {
+		return err
+	}
+	if definition == "" {
+		return &UsageError{ctx.Command}
+	}
+	if err := canManageTemplates(ctx); err != nil {
+		return err
+	}
+
+	// check the definition now rather than when it's first used
+	if _, err := parsePollSpec(definition); err != nil {
+		return err
+	}
+
+	if err := templates.Set(ctx.GuildID(), name, definition); err != nil {
+		return err
+	}
+
+	fmt.Printf("saved template %s in %s\n", name, ctx.GuildID())
+	return ctx.Reply(ctx.T("template.saved", name, ctx.Prefix+"template use "+name))
+}
+
+func handleTemplateUse(ctx *Context) error {
+	name, overrides, err := templateName(ctx)
+	if err != nil {
+		return err
+	}
+
+	definition, ok := templates.Get(ctx.GuildID(), name)
+	if !ok {
+		return newUserError("template.not_found", name)
+	}
+
+	spec, err := parsePollSpec(definition)
+	if err != nil {
+		return err
+	}
+	if err := spec.parse(overrides); err != nil {
+		return err
+	}
+
+	e, err := newPollEntry(ctx.Session, ctx.GuildID(), ctx.Message.ChannelID, ctx.Message.Author.ID, spec)
+	if err != nil {
+		return err
+	}
+
+	return openPoll(ctx.Session, e)
+}
+
+func handleTemplateList(ctx *Context) error {
+	names := templates.Names(ctx.GuildID())
+	if len(names) == 0 {
+		return ctx.Reply(ctx.T("template.none"))
+	}
+
+	lines := []string{}
+	for _, name := range names {
+		definition, _ := templates.Get(ctx.GuildID(), name)
+		lines = append(lines, fmt.Sprintf("**%s** `%s`", name, strings.Replace(definition, "`", "'", -1)))
+	}
+
+	for _, msg := range joinLimited(lines, "\n", maxMessageLength) {
+		if err := ctx.Reply(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func handleTemplateDelete(ctx *Context) error {
+	name, _, err := templateName(ctx)
+	if err != nil {
+		return err
+	}
+	if err := canManageTemplates(ctx); err != nil {
+		return err
+	}
+
+	ok, err := templates.Delete(ctx.GuildID(), name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newUserError("template.not_found", name)
+	}
+
+	return ctx.Reply(ctx.T("template.deleted", name))
+}
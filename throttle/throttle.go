@@ -0,0 +1,117 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often a Limiter forgets buckets that have refilled
+const pruneInterval = time.Minute
+
+// Rate describes a token bucket. Burst events are allowed at once, and one
+// more token is added every Every.
+type Rate struct {
+	Burst int
+	Every time.Duration
+}
+
+// Unlimited reports whether r places no limit on events
+func (r Rate) Unlimited() bool {
+	return r.Burst <= 0 || r.Every <= 0
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps a separate token bucket for each key
+type Limiter struct {
+	rate Rate
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+// NewLimiter creates a Limiter whose buckets all follow rate
+func NewLimiter(rate Rate) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket at time now. If the bucket is empty it
+// returns false and how long until a token is available.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	if l.rate.Unlimited() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) > pruneInterval {
+		l.prune(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate.Burst), last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) * float64(l.rate.Every))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// Wait returns how long until key's bucket has a token at time now, without
+// taking it
+func (l *Limiter) Wait(key string, now time.Time) time.Duration {
+	if l.rate.Unlimited() {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		return 0
+	}
+	l.refill(b, now)
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) * float64(l.rate.Every))
+	}
+	return 0
+}
+
+// refill adds the tokens earned since the bucket was last used
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(l.rate.Every)
+		if max := float64(l.rate.Burst); b.tokens > max {
+			b.tokens = max
+		}
+		b.last = now
+	}
+}
+
+// prune forgets buckets that are full again, they behave like new ones
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= float64(l.rate.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
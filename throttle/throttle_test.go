@@ -0,0 +1,112 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type call struct {
+		key   string
+		after time.Duration
+		ok    bool
+		wait  time.Duration
+	}
+
+	tests := []struct {
+		rate  Rate
+		calls []call
+	}{
+		{
+			Rate{2, time.Second},
+			[]call{
+				{"a", 0, true, 0},
+				{"a", 0, true, 0},
+				{"a", 0, false, time.Second},
+				{"a", 500 * time.Millisecond, false, 500 * time.Millisecond},
+				{"a", time.Second, true, 0},
+			},
+		},
+		{
+			Rate{1, time.Minute},
+			[]call{
+				{"a", 0, true, 0},
+				{"b", 0, true, 0},
+				{"a", 0, false, time.Minute},
+				{"b", time.Minute, true, 0},
+			},
+		},
+		{
+			Rate{1, 0},
+			[]call{
+				{"a", 0, true, 0},
+				{"a", 0, true, 0},
+			},
+		},
+		{
+			Rate{3, time.Second},
+			[]call{
+				{"a", 0, true, 0},
+				{"a", 0, true, 0},
+				{"a", 0, true, 0},
+				{"a", time.Hour, true, 0},
+				{"a", time.Hour, true, 0},
+				{"a", time.Hour, true, 0},
+				{"a", time.Hour, false, time.Second},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		l := NewLimiter(test.rate)
+
+		for j, c := range test.calls {
+			ok, wait := l.Allow(c.key, start.Add(c.after))
+
+			if ok != c.ok || wait != c.wait {
+				t.Errorf("test %d call %d: Allow returned (%v, %v)\nWant: (%v, %v)",
+					i, j, ok, wait, c.ok, c.wait)
+			}
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(Rate{1, time.Second})
+
+	if wait := l.Wait("a", start); wait != 0 {
+		t.Errorf("Wait on a new bucket returned %v, want 0", wait)
+	}
+
+	l.Allow("a", start)
+	for i := 0; i < 2; i++ {
+		if wait := l.Wait("a", start.Add(250*time.Millisecond)); wait != 750*time.Millisecond {
+			t.Errorf("Wait on an empty bucket returned %v, want %v", wait, 750*time.Millisecond)
+		}
+	}
+
+	if wait := l.Wait("a", start.Add(time.Second)); wait != 0 {
+		t.Errorf("Wait on a refilled bucket returned %v, want 0", wait)
+	}
+	if ok, _ := l.Allow("a", start.Add(time.Second)); !ok {
+		t.Errorf("Wait took the token it reported")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(Rate{1, time.Second})
+
+	l.Allow("a", start)
+	l.Allow("b", start.Add(2*time.Minute))
+
+	if _, ok := l.buckets["a"]; ok {
+		t.Errorf("Allow didn't prune a full bucket")
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Errorf("Allow pruned a bucket that was just used")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// voicePolicies are how a voice poll's voters follow people joining and
+// leaving its voice channel. Votes cast by people who then leave still count,
+// they were eligible when they cast them; leaving only stops them voting again.
+var voicePolicies = map[string]bool{
+	// fixed keeps the voters the poll started with
+	"fixed": true,
+	// add lets people who join vote too
+	"add": true,
+	// remove stops people who leave from voting
+	"remove": true,
+	// follow does both
+	"follow": true,
+}
+
+// voiceOccupants returns the voice channel userID is in and the IDs of the
+// people in it, userID included. Bots never vote.
+func voiceOccupants(s *discordgo.Session, guildID, userID string) (string, []string, error) {
+	g, err := s.State.Guild(guildID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.State.RLock()
+	voiceStates := append([]*discordgo.VoiceState(nil), g.VoiceStates...)
+	s.State.RUnlock()
+
+	channelID := ""
+	for _, vs := range voiceStates {
+		if vs.UserID == userID {
+			channelID = vs.ChannelID
+		}
+	}
+	if channelID == "" {
+		return "", nil, newUserError("poll.not_in_voice")
+	}
+
+	occupants := []string{}
+	for _, vs := range voiceStates {
+		if vs.ChannelID == channelID && !isBot(s, guildID, vs.UserID) {
+			occupants = append(occupants, vs.UserID)
+		}
+	}
+
+	return channelID, occupants, nil
+}
+
+// isBot reports whether userID is a bot, as far as the state knows
+func isBot(s *discordgo.Session, guildID, userID string) bool {
+	if userID == botID {
+		return true
+	}
+
+	m, err := s.State.Member(guildID, userID)
+	return err == nil && m.User.Bot
+}
+
+// handleVoiceStateUpdate lets the voters of open voice polls follow people
+// joining and leaving their channel, as each poll's policy says
+func handleVoiceStateUpdate(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+	if isBot(s, v.GuildID, v.UserID) {
+		return
+	}
+
+	ids := []int{}
+	polls.Each(func(e *pollEntry) {
+		if !e.Closed && e.GuildID == v.GuildID && e.VoiceChannelID != "" {
+			ids = append(ids, e.ID)
+		}
+	})
+
+	for _, id := range ids {
+		changed := false
+		err := polls.Update(id, func(e *pollEntry) error {
+			joined := v.ChannelID == e.VoiceChannelID
+			eligible := contains(e.Eligible, v.UserID)
+
+			switch {
+			case joined && !eligible && (e.VoicePolicy == "add" || e.VoicePolicy == "follow"):
+				e.Eligible = append(e.Eligible, v.UserID)
+				changed = true
+			case !joined && eligible && (e.VoicePolicy == "remove" || e.VoicePolicy == "follow"):
+				eligible := []string{}
+				for _, user := range e.Eligible {
+					if user != v.UserID {
+						eligible = append(eligible, user)
+					}
+				}
+				e.Eligible = eligible
+				changed = true
+			}
+
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("failed to update voters of poll %d: %v\n", id, err)
+			continue
+		}
+
+		if changed {
+			refresher.Refresh(s, id)
+			autoClosePoll(s, id)
+		}
+	}
+}
+
+func handleVCPoll(ctx *Context) error {
+	if ctx.GuildID() == "" {
+		return newUserError("poll.not_in_voice")
+	}
+
+	spec, err := parsePollSpec(ctx.Raw)
+	if err != nil {
+		return err
+	}
+	spec.Voice = true
+
+	e, err := newPollEntry(ctx.Session, ctx.GuildID(), ctx.Message.ChannelID, ctx.Message.Author.ID, spec)
+	if err != nil {
+		return err
+	}
+
+	return openPoll(ctx.Session, e)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mroseman95/discord-poll-bot/poll"
+)
+
+func TestPollVotersVoiceOnly(t *testing.T) {
+	spec := &pollSpec{Voice: true, Voters: []string{"alice"}}
+
+	expected := newUserError("poll.voice_voters")
+	if _, err := pollVoters(nil, &pollEntry{}, spec); !reflect.DeepEqual(err, expected) {
+		t.Errorf("pollVoters of a voice poll with --voters returned %v, want %v", err, expected)
+	}
+}
+
+func TestAutoCloseEmptyVoicePoll(t *testing.T) {
+	p, _ := poll.NewPoll([]string{"yes", "no"})
+	e := &pollEntry{ID: -1, AutoClose: true, VoiceChannelID: "voice", Eligible: []string{}, Poll: p}
+	polls.Polls[e.ID] = e
+	defer delete(polls.Polls, e.ID)
+
+	// everyone left, which isn't everyone having voted
+	autoClosePoll(nil, e.ID)
+	if e.Closed {
+		t.Errorf("autoClosePoll closed a voice poll with nobody left to vote")
+	}
+}
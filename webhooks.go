@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	webhookNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	webhookURLPattern  = regexp.MustCompile(`^https://(?:(?:ptb|canary)\.)?discord(?:app)?\.com/api(?:/v\d+)?/webhooks/(\d+)/([\w-]+)/?$`)
+)
+
+// webhookFormats are the ways poll results can be posted to a webhook
+var webhookFormats = map[string]bool{"text": true, "embed": true}
+
+// resultHook is a webhook poll results are published to
+type resultHook struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+	// ChannelID is where the bot created the webhook, empty for ones it was
+	// given the URL of
+	ChannelID string `json:"channel_id,omitempty"`
+	// Username and AvatarURL override the webhook's own, when set
+	Username  string `json:"username,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	// Format is one of webhookFormats
+	Format string `json:"format"`
+}
+
+// webhookStore keeps each guild's result webhooks by name
+type webhookStore struct {
+	guildStore
+}
+
+var webhooks = &webhookStore{newGuildStore("webhooks.json")}
+
+// Get returns guildID's webhook name
+func (ws *webhookStore) Get(guildID, name string) (resultHook, bool) {
+	var h resultHook
+	ok := ws.get(guildID, name, &h)
+	return h, ok
+}
+
+// Set saves h as guildID's webhook name, replacing any it had
+func (ws *webhookStore) Set(guildID, name string, h resultHook) error {
+	return ws.set(guildID, name, h)
+}
+
+// webhookFlags are the --flags that set how results look on a webhook
+var webhookFlags = map[string]func(h *resultHook, value string) error{
+	"username": func(h *resultHook, value string) error {
+		if len(value) > 80 {
+			return newUserError("webhook.invalid_username", value)
+		}
+		h.Username = value
+		return nil
+	},
+	"avatar": func(h *resultHook, value string) error {
+		if value != "" {
+			if u, err := url.Parse(value); err != nil || u.Scheme != "https" || u.Host == "" {
+				return newUserError("webhook.invalid_avatar", value)
+			}
+		}
+		h.AvatarURL = value
+		return nil
+	},
+	"format": func(h *resultHook, value string) error {
+		value = strings.ToLower(value)
+		if !webhookFormats[value] {
+			return newUserError("webhook.invalid_format", value)
+		}
+		h.Format = value
+		return nil
+	},
+}
+
+// parseWebhookFlags applies the flags in raw to h, returning raw without them
+func parseWebhookFlags(h *resultHook, raw string) (string, error) {
+	for _, m := range flagPattern.FindAllStringSubmatch(raw, -1) {
+		set, ok := webhookFlags[m[1]]
+		if !ok {
+			return "", newUserError("webhook.unknown_flag", m[1])
+		}
+		if err := set(h, strings.Trim(m[2], `"`)); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimSpace(flagPattern.ReplaceAllString(raw, "")), nil
+}
+
+// publishResults posts the results of poll e to each of its webhooks
+func publishResults(s *discordgo.Session, e *pollEntry) {
+	lang := language(e.GuildID, "")
+	for _, name := range e.Webhooks {
+		h, ok := webhooks.Get(e.GuildID, name)
+		if !ok {
+			fmt.Printf("webhook %s of poll %d is gone\n", name, e.ID)
+			continue
+		}
+
+		if err := s.WebhookExecute(h.ID, h.Token, false, webhookResults(e, h, lang)); err != nil {
+			fmt.Printf("failed to publish poll %d to webhook %s: %v\n", e.ID, name, err)
+		}
+	}
+}
+
+// webhookResults renders the results of poll e the way h wants them
+func webhookResults(e *pollEntry, h resultHook, lang string) *discordgo.WebhookParams {
+	params := &discordgo.WebhookParams{Username: h.Username, AvatarURL: h.AvatarURL}
+
+	results := pollResults(e, lang)
+	if h.Format != "embed" {
+		params.Content = results
+		return params
+	}
+
+	title := e.Question
+	if title == "" {
+		title = catalog.T(lang, "poll.untitled")
+	}
+	// the first line of the results repeats the question, the embed's title
+	// already shows it
+	lines := strings.SplitN(results, "\n", 2)
+	params.Embeds = []*discordgo.MessageEmbed{{
+		Title:       title,
+		Description: lines[len(lines)-1],
+		Color:       pollColor,
+		Footer:      &discordgo.MessageEmbedFooter{Text: catalog.T(lang, "poll.footer", e.ID)},
+	}}
+
+	return params
+}
+
+var webhookSubcommands = map[string]HandlerFunc{
+	"create": handleWebhookCreate,
+	"add":    handleWebhookAdd,
+	"set":    handleWebhookSet,
+	"list":   handleWebhookList,
+	"delete": handleWebhookDelete,
+}
+
+func handleWebhook(ctx *Context) error {
+	if ctx.GuildID() == "" {
+		return newUserError("webhook.guild_only")
+	}
+
+	sub, ok := webhookSubcommands[strings.ToLower(ctx.Args[0])]
+	if !ok {
+		return &UsageError{ctx.Command}
+	}
+
+	ctx.Raw = strings.TrimSpace(strings.TrimPrefix(ctx.Raw, ctx.Args[0]))
+	ctx.Args = ctx.Args[1:]
+	return sub(ctx)
+}
+
+// webhookName returns the webhook named by the first argument, and the rest
+// of the command after it
+func webhookName(ctx *Context) (string, string, error) {
+	if len(ctx.Args) == 0 {
+		return "", "", &UsageError{ctx.Command}
+	}
+
+	name := strings.ToLower(ctx.Args[0])
+	if !webhookNamePattern.MatchString(name) {
+		return "", "", newUserError("webhook.invalid_name", ctx.Args[0])
+	}
+
+	return name, strings.TrimSpace(strings.TrimPrefix(ctx.Raw, ctx.Args[0])), nil
+}
+
+// handleWebhookCreate has the bot make a webhook in a channel of this guild,
+// or take over the one it made there before under the same name
+func handleWebhookCreate(ctx *Context) error {
+	name, rest, err := webhookName(ctx)
+	if err != nil {
+		return err
+	}
+
+	h := resultHook{Format: "text"}
+	rest, err = parseWebhookFlags(&h, rest)
+	if err != nil {
+		return err
+	}
+
+	channelID := ctx.Message.ChannelID
+	if rest != "" {
+		var ok bool
+		if channelID, ok = parseChannel(rest); !ok {
+			return newUserError("mirror.invalid_channel", rest)
+		}
+		if c, err := channel(ctx.Session, channelID); err != nil || c.GuildID != ctx.GuildID() {
+			return newUserError("mirror.invalid_channel", rest)
+		}
+	}
+
+	ok, err := hasPermissions(ctx.Session, botID, channelID, discordgo.PermissionManageWebhooks)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newUserError("webhook.no_permission", channelID)
+	}
+
+	// channels only hold a few webhooks, so reuse ours rather than piling
+	// up new ones
+	existing, err := ctx.Session.ChannelWebhooks(channelID)
+	if err != nil {
+		return err
+	}
+	var hook *discordgo.Webhook
+	for _, w := range existing {
+		if w.Name == name && w.User != nil && w.User.ID == botID && w.Token != "" {
+			hook = w
+			break
+		}
+	}
+	if hook == nil {
+		if hook, err = ctx.Session.WebhookCreate(channelID, name, ""); err != nil {
+			return err
+		}
+	}
+
+	h.ID, h.Token, h.ChannelID = hook.ID, hook.Token, channelID
+	if err := webhooks.Set(ctx.GuildID(), name, h); err != nil {
+		return err
+	}
+
+	fmt.Printf("created webhook %s in %s\n", name, channelID)
+	return ctx.Reply(ctx.T("webhook.created", name, channelID, "--webhooks="+name))
+}
+
+// handleWebhookAdd saves a webhook by its URL, which can be in a guild the bot
+// isn't in
+func handleWebhookAdd(ctx *Context) error {
+	// the URL is as good as a password, so don't leave it in the channel
+	if err := ctx.Session.ChannelMessageDelete(ctx.Message.ChannelID, ctx.Message.ID); err != nil {
+		fmt.Printf("failed to delete webhook URL message: %v\n", err)
+	}
+
+	name, rest, err := webhookName(ctx)
+	if err != nil {
+		return err
+	}
+
+	h := resultHook{Format: "text"}
+	rest, err = parseWebhookFlags(&h, rest)
+	if err != nil {
+		return err
+	}
+
+	m := webhookURLPattern.FindStringSubmatch(strings.Trim(rest, "<>"))
+	if m == nil {
+		return newUserError("webhook.invalid_url")
+	}
+	h.ID, h.Token = m[1], m[2]
+
+	// make sure the URL works before polls rely on it
+	if _, err := ctx.Session.WebhookWithToken(h.ID, h.Token); err != nil {
+		return newUserError("webhook.invalid_url")
+	}
+
+	if err := webhooks.Set(ctx.GuildID(), name, h); err != nil {
+		return err
+	}
+
+	fmt.Printf("added webhook %s in %s\n", name, ctx.GuildID())
+	return ctx.Reply(ctx.T("webhook.added", name, "--webhooks="+name))
+}
+
+func handleWebhookSet(ctx *Context) error {
+	name, rest, err := webhookName(ctx)
+	if err != nil {
+		return err
+	}
+
+	h, ok := webhooks.Get(ctx.GuildID(), name)
+	if !ok {
+		return newUserError("webhook.not_found", name)
+	}
+	rest, err = parseWebhookFlags(&h, rest)
+	if err != nil {
+		return err
+	}
+	if rest != "" {
+		return &UsageError{ctx.Command}
+	}
+
+	if err := webhooks.Set(ctx.GuildID(), name, h); err != nil {
+		return err
+	}
+
+	return ctx.Reply(ctx.T("webhook.updated", name))
+}
+
+func handleWebhookList(ctx *Context) error {
+	names := webhooks.Names(ctx.GuildID())
+	if len(names) == 0 {
+		return ctx.Reply(ctx.T("webhook.none"))
+	}
+
+	lines := []string{}
+	for _, name := range names {
+		h, _ := webhooks.Get(ctx.GuildID(), name)
+		where := ctx.T("webhook.external")
+		if h.ChannelID != "" {
+			where = "<#" + h.ChannelID + ">"
+		}
+
+		line := fmt.Sprintf("**%s** %s · %s", name, where, h.Format)
+		if h.Username != "" {
+			line += " · " + h.Username
+		}
+		lines = append(lines, line)
+	}
+
+	for _, msg := range joinLimited(lines, "\n", maxMessageLength) {
+		if err := ctx.Reply(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func handleWebhookDelete(ctx *Context) error {
+	name, _, err := webhookName(ctx)
+	if err != nil {
+		return err
+	}
+
+	h, ok := webhooks.Get(ctx.GuildID(), name)
+	if !ok {
+		return newUserError("webhook.not_found", name)
+	}
+
+	// webhooks the bot made go away with it, ones it was given are left be
+	if h.ChannelID != "" {
+		if err := ctx.Session.WebhookDelete(h.ID); err != nil {
+			fmt.Printf("failed to delete webhook %s: %v\n", name, err)
+		}
+	}
+
+	if _, err := webhooks.Delete(ctx.GuildID(), name); err != nil {
+		return err
+	}
+
+	return ctx.Reply(ctx.T("webhook.deleted", name))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWebhookURLPattern(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected []string
+	}{
+		{"https://discord.com/api/webhooks/123/abc-DEF_ghi", []string{"123", "abc-DEF_ghi"}},
+		{"https://discordapp.com/api/webhooks/123/token/", []string{"123", "token"}},
+		{"https://canary.discord.com/api/v6/webhooks/123/token", []string{"123", "token"}},
+		{"https://ptb.discordapp.com/api/webhooks/123/token", []string{"123", "token"}},
+		{"http://discord.com/api/webhooks/123/token", nil},
+		{"https://evil.com/api/webhooks/123/token", nil},
+		{"https://discord.com.evil.com/api/webhooks/123/token", nil},
+		{"https://discord.com/api/webhooks/abc/token", nil},
+		{"https://discord.com/api/webhooks/123", nil},
+		{"https://discord.com/api/webhooks/123/token/extra", nil},
+		{"https://discord.com/api/webhooks/123/token?wait=true", nil},
+	}
+
+	for _, test := range tests {
+		var got []string
+		if m := webhookURLPattern.FindStringSubmatch(test.url); m != nil {
+			got = m[1:]
+		}
+
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("webhookURLPattern matched %q as %q\nWant: %q", test.url, got, test.expected)
+		}
+	}
+}